@@ -35,6 +35,7 @@ func main() {
 	}
 
 	commandPlugin := commandplugin.New()
+	planetsidetwoPlugin := planetsidetwoplugin.New(commandPlugin)
 
 	config := &discordgobot.GobotConf{
 		OwnerUserID: ownerUserID,
@@ -60,9 +61,9 @@ func main() {
 
 	bot.RegisterPlugin(commandPlugin)
 	bot.RegisterPlugin(inviteplugin.New())
-	bot.RegisterPlugin(statsplugin.New(VERSION))
-	bot.RegisterPlugin(planetsidetwoplugin.New())
-	bot.RegisterPlugin(translatorplugin.New())
+	bot.RegisterPlugin(statsplugin.New(VERSION, planetsidetwoPlugin))
+	bot.RegisterPlugin(planetsidetwoPlugin)
+	bot.RegisterPlugin(translatorplugin.New(commandPlugin))
 
 	bot.Open()
 
@@ -40,6 +40,28 @@ func (p *commandPlugin) Commands() []*discordgobot.CommandDefinition {
 			Description: "Set the command prefix for this server",
 			Callback:    p.runSetPrefixCommand,
 		},
+		&discordgobot.CommandDefinition{
+			CommandID: "command-settheme",
+			Triggers: []string{
+				"settheme",
+			},
+			PermissionLevel: discordgobot.PERMISSION_ADMIN,
+			ExposureLevel:   discordgobot.EXPOSURE_PUBLIC,
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Optional: false,
+					Pattern:  "[a-zA-Z]+",
+					Alias:    "key",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  "\\S+",
+					Alias:    "color",
+				},
+			},
+			Description: "Set an embed color for this server: house, vs, nc, tr or nso, or reset",
+			Callback:    p.runSetThemeCommand,
+		},
 	}
 }
 
@@ -81,3 +103,22 @@ func (p *commandPlugin) GetGuildPrefix(guildID string) (*string, error) {
 
 	return guildProfile.Prefix, nil
 }
+
+func (p *commandPlugin) GetGuildPS2Platform(guildID string) (*string, error) {
+	guildProfile, err := p.repository.getGuildProfile(guildID)
+
+	if err != nil {
+		log.Printf("Failed to get guild profile for '%s': %s", guildID, err)
+		return nil, err
+	}
+
+	if guildProfile == nil {
+		return nil, nil
+	}
+
+	return guildProfile.PS2Platform, nil
+}
+
+func (p *commandPlugin) SetGuildPS2Platform(guildID string, userID string, platform string) error {
+	return p.repository.updateGuildPS2Platform(guildID, userID, platform)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -39,11 +40,18 @@ func (r *repository) initRepository() {
 		log.Fatal("%q: %s\n", err, initSQL)
 	}
 
+	for _, migration := range migrationSQL {
+		_, err = db.Exec(migration)
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			log.Fatalf("%q: %s\n", err, migration)
+		}
+	}
+
 	r.Database = db
 }
 
 func (r *repository) getGuildProfile(guildID string) (*guildProfile, error) {
-	stmt, err := r.Database.Prepare("select id, prefix, lastChangedBy, lastChangedDate from guild_profile where id = ?")
+	stmt, err := r.Database.Prepare("select id, prefix, ps2Platform, theme, lastChangedBy, lastChangedDate from guild_profile where id = ?")
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +61,8 @@ func (r *repository) getGuildProfile(guildID string) (*guildProfile, error) {
 	err = stmt.QueryRow(guildID).Scan(
 		&record.ID,
 		&record.Prefix,
+		&record.PS2Platform,
+		&record.Theme,
 		&record.LastChangedBy,
 		&record.LastChangedDate)
 	if err != nil {
@@ -81,3 +91,37 @@ func (r *repository) updateGuildPrefix(guildID string, userID string, prefix str
 
 	return nil
 }
+
+func (r *repository) updateGuildPS2Platform(guildID string, userID string, platform string) error {
+	stmt, err := r.Database.Prepare("insert into guild_profile (id, ps2Platform, lastChangedBy, lastChangedDate) values (?,?,?,?) on conflict (id) do update set ps2Platform = excluded.ps2Platform, lastChangedBy = excluded.lastChangedBy, lastChangedDate = excluded.lastChangedDate")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(guildID, platform, userID, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) updateGuildTheme(guildID string, userID string, theme string) error {
+	stmt, err := r.Database.Prepare("insert into guild_profile (id, theme, lastChangedBy, lastChangedDate) values (?,?,?,?) on conflict (id) do update set theme = excluded.theme, lastChangedBy = excluded.lastChangedBy, lastChangedDate = excluded.lastChangedDate")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(guildID, theme, userID, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
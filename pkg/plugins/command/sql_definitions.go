@@ -11,9 +11,17 @@ CREATE TABLE IF NOT EXISTS guild_profile (
 );
 `
 
+// migrationSQL holds schema changes applied to databases created by earlier versions.
+var migrationSQL = []string{
+	`ALTER TABLE guild_profile ADD COLUMN ps2Platform TEXT;`,
+	`ALTER TABLE guild_profile ADD COLUMN theme TEXT;`,
+}
+
 type guildProfile struct {
 	ID              string
 	Prefix          *string
+	PS2Platform     *string
+	Theme           *string
 	LastChangedBy   *string
 	LastChangedDate *time.Time
 }
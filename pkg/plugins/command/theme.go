@@ -0,0 +1,142 @@
+package commandplugin
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+// themeKeys are the embed colors a guild can override. 'house' colors embeds that are not
+// tied to a faction; the rest override the faction palette.
+var themeKeys = []string{"house", "vs", "nc", "tr", "nso"}
+
+// parseTheme reads a stored theme such as 'house=1f8b4c,vs=6a1b9a', skipping anything malformed.
+func parseTheme(value string) map[string]int {
+	theme := make(map[string]int)
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		color, err := parseColor(parts[1])
+		if err != nil {
+			continue
+		}
+
+		theme[strings.ToLower(parts[0])] = color
+	}
+
+	return theme
+}
+
+func formatTheme(theme map[string]int) string {
+	entries := make([]string, 0, len(theme))
+	for key, color := range theme {
+		entries = append(entries, fmt.Sprintf("%s=%06x", key, color))
+	}
+
+	sort.Strings(entries)
+
+	return strings.Join(entries, ",")
+}
+
+func parseColor(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "#"), "0x")
+
+	color, err := strconv.ParseInt(value, 16, 32)
+	if err != nil || len(value) != 6 {
+		return 0, fmt.Errorf("Invalid color '%s'", value)
+	}
+
+	return int(color), nil
+}
+
+func isThemeKey(key string) bool {
+	for _, themeKey := range themeKeys {
+		if themeKey == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetGuildTheme returns the guild's embed color overrides keyed by theme key. Keys that
+// were not overridden are missing, so callers fall back to their own defaults.
+func (p *commandPlugin) GetGuildTheme(guildID string) (map[string]int, error) {
+	guildProfile, err := p.repository.getGuildProfile(guildID)
+
+	if err != nil {
+		log.Printf("Failed to get guild profile for '%s': %s", guildID, err)
+		return nil, err
+	}
+
+	if guildProfile == nil || guildProfile.Theme == nil {
+		return map[string]int{}, nil
+	}
+
+	return parseTheme(*guildProfile.Theme), nil
+}
+
+func (p *commandPlugin) runSetThemeCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	key := strings.ToLower(args["key"])
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil {
+		return
+	}
+
+	theme, err := p.GetGuildTheme(channel.GuildID)
+	if err != nil {
+		p.Lock()
+		client.SendMessage(message.Channel(), "Failed to get the current theme.")
+		p.Unlock()
+		return
+	}
+
+	var reply string
+
+	switch {
+	case key == "reset":
+		theme = map[string]int{}
+		reply = "Theme reset!"
+	case !isThemeKey(key):
+		p.Lock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("Unknown theme color '%s'. Use %s or reset.", key, strings.Join(themeKeys, ", ")))
+		p.Unlock()
+		return
+	case args["color"] == "" || strings.ToLower(args["color"]) == "default":
+		delete(theme, key)
+		reply = fmt.Sprintf("%s color reset!", strings.ToUpper(key))
+	default:
+		color, err := parseColor(args["color"])
+		if err != nil {
+			p.Lock()
+			client.SendMessage(message.Channel(), fmt.Sprintf("%s. Use a hex color such as #1f8b4c.", err))
+			p.Unlock()
+			return
+		}
+		theme[key] = color
+		reply = fmt.Sprintf("%s color set to #%06x!", strings.ToUpper(key), color)
+	}
+
+	err = p.repository.updateGuildTheme(channel.GuildID, message.UserID(), formatTheme(theme))
+
+	p.Lock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to set theme.")
+	} else {
+		client.SendMessage(message.Channel(), reply)
+	}
+
+	p.Unlock()
+}
@@ -0,0 +1,352 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	metagameEventStarted = "started"
+	metagameEventEnded   = "ended"
+)
+
+var rolePattern = regexp.MustCompile(`^<@&([0-9]+)>$`)
+
+// alertWatcher keeps one event stream subscription per world that has alert subscribers.
+type alertWatcher struct {
+	sync.Mutex
+	unsubscribes   map[string]func()
+	metagameEvents map[string]*censusMetagameEvent
+	census         *censusDataSource
+}
+
+func newAlertWatcher() *alertWatcher {
+	return &alertWatcher{
+		unsubscribes:   make(map[string]func()),
+		metagameEvents: make(map[string]*censusMetagameEvent),
+		census:         newCensusDataSource(),
+	}
+}
+
+// metagameEvent describes an alert type, remembering the answer since alert types rarely change.
+func (w *alertWatcher) metagameEvent(metagameEventID string, platform string) *censusMetagameEvent {
+	w.Lock()
+	metagameEvent, ok := w.metagameEvents[metagameEventID]
+	w.Unlock()
+
+	if ok {
+		return metagameEvent
+	}
+
+	metagameEvent, err := w.census.getMetagameEvent(metagameEventID, platform)
+	if err != nil {
+		log.Printf("Failed to get metagame event '%s': %s", metagameEventID, err)
+		return &censusMetagameEvent{
+			MetagameEventID: metagameEventID,
+			Name:            censusLocalizedString{En: "Alert"},
+		}
+	}
+
+	w.Lock()
+	w.metagameEvents[metagameEventID] = metagameEvent
+	w.Unlock()
+
+	return metagameEvent
+}
+
+// startAlerts restores the world subscriptions saved before a restart.
+func (p *planetsidetwoPlugin) startAlerts() {
+	subscriptions, err := p.repository.getAlertSubscriptions()
+	if err != nil {
+		log.Printf("Failed to get alert subscriptions: %s", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		p.watchWorldAlerts(subscription.Platform, subscription.WorldID)
+	}
+}
+
+func (p *planetsidetwoPlugin) watchWorldAlerts(platform string, worldID string) {
+	key := platform + "/" + worldID
+
+	p.alerts.Lock()
+	defer p.alerts.Unlock()
+
+	if _, ok := p.alerts.unsubscribes[key]; ok {
+		return
+	}
+
+	filter := eventFilter{
+		Worlds:     []string{worldID},
+		EventNames: []string{eventMetagameEvent},
+	}
+
+	p.alerts.unsubscribes[key] = p.events.get(platform).subscribe(filter, func(event planetsideEvent) {
+		if metagameEvent, ok := event.(*MetagameEvent); ok {
+			p.sendAlertNotifications(platform, metagameEvent)
+		}
+	})
+}
+
+// unwatchWorldAlerts drops the world's event subscription once no channel wants its alerts.
+func (p *planetsidetwoPlugin) unwatchWorldAlerts(platform string, worldID string) {
+	subscriptions, err := p.repository.getWorldAlertSubscriptions(platform, worldID)
+	if err != nil || len(subscriptions) > 0 {
+		return
+	}
+
+	key := platform + "/" + worldID
+
+	p.alerts.Lock()
+	defer p.alerts.Unlock()
+
+	if unsubscribe, ok := p.alerts.unsubscribes[key]; ok {
+		unsubscribe()
+		delete(p.alerts.unsubscribes, key)
+	}
+}
+
+func (p *planetsidetwoPlugin) sendAlertNotifications(platform string, event *MetagameEvent) {
+	if event.MetagameEventState != metagameEventStarted && event.MetagameEventState != metagameEventEnded {
+		return
+	}
+
+	session := p.session()
+	if session == nil {
+		return
+	}
+
+	subscriptions, err := p.repository.getWorldAlertSubscriptions(platform, event.WorldID())
+	if err != nil {
+		log.Printf("Failed to get alert subscriptions for world '%s': %s", event.WorldID(), err)
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	embed, winnerFactionID := p.createAlertEmbed(platform, event)
+
+	for _, subscription := range subscriptions {
+		themed := *embed
+		themed.Color = p.guildEmbedColor(subscription.GuildID, winnerFactionID)
+
+		send := &discordgo.MessageSend{
+			Embed: &themed,
+		}
+
+		if subscription.RoleID != nil && *subscription.RoleID != "" {
+			send.Content = fmt.Sprintf("<@&%s>", *subscription.RoleID)
+		}
+
+		_, err := session.ChannelMessageSendComplex(subscription.ChannelID, send)
+		if err != nil {
+			log.Printf("Failed to send alert to channel '%s': %s", subscription.ChannelID, err)
+		}
+	}
+}
+
+// createAlertEmbed describes the alert, returning the winning faction once it has ended or 0 otherwise.
+func (p *planetsidetwoPlugin) createAlertEmbed(platform string, event *MetagameEvent) (*discordgo.MessageEmbed, int) {
+	metagameEvent := p.alerts.metagameEvent(event.MetagameEventID, platform)
+
+	embed := &discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("%s %s on %s", metagameEvent.Name.En, event.MetagameEventState, worldName(event.WorldID())),
+		Timestamp: event.Time().Format("2006-01-02T15:04:05Z"),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{
+				Name:   "Continent",
+				Value:  zoneName(event.ZoneID),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Alert",
+				Value:  metagameEvent.Name.En,
+				Inline: true,
+			},
+		},
+	}
+
+	if metagameEvent.Description.En != "" {
+		embed.Description = metagameEvent.Description.En
+	}
+
+	if event.MetagameEventState != metagameEventEnded {
+		return embed, 0
+	}
+
+	territories := []struct {
+		factionID int
+		percent   float64
+	}{
+		{1, parseTerritory(event.FactionVS)},
+		{2, parseTerritory(event.FactionNC)},
+		{3, parseTerritory(event.FactionTR)},
+	}
+
+	territoryLines := make([]string, len(territories))
+	for i, territory := range territories {
+		territoryLines[i] = fmt.Sprintf("%s: %0.1f%%", getFactionName(territory.factionID), territory.percent)
+	}
+
+	sort.SliceStable(territories, func(i, j int) bool {
+		return territories[i].percent > territories[j].percent
+	})
+
+	winner, winnerFactionID := getFactionName(territories[0].factionID), territories[0].factionID
+	if territories[0].percent == territories[1].percent {
+		winner, winnerFactionID = "Draw", 0
+	}
+
+	embed.Fields = append(embed.Fields,
+		&discordgo.MessageEmbedField{
+			Name:   "Territory",
+			Value:  strings.Join(territoryLines, "\n"),
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Winner",
+			Value:  winner,
+			Inline: true,
+		})
+
+	return embed, winnerFactionID
+}
+
+func parseTerritory(value string) float64 {
+	percent, _ := strconv.ParseFloat(value, 64)
+	return percent
+}
+
+func (p *planetsidetwoPlugin) runAlertsCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Alerts can only be configured in a server.")
+		p.RUnlock()
+		return
+	}
+
+	action := strings.ToLower(args["action"])
+	if action == "list" {
+		p.sendAlertSubscriptions(client, message, channel.GuildID)
+		return
+	}
+
+	if args["world"] == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Specify a server such as Emerald, Connery or Miller.")
+		p.RUnlock()
+		return
+	}
+
+	worldID, ok := findWorld(args["world"])
+	if !ok {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("Unknown server '%s'.", args["world"]))
+		p.RUnlock()
+		return
+	}
+
+	platform := worldPlatform(worldID)
+
+	if action == "unsubscribe" {
+		removed, err := p.repository.removeAlertSubscription(message.Channel(), platform, worldID)
+		if err == nil && removed {
+			p.unwatchWorldAlerts(platform, worldID)
+		}
+
+		p.RLock()
+
+		if err != nil {
+			client.SendMessage(message.Channel(), "Failed to unsubscribe from alerts.")
+		} else if !removed {
+			client.SendMessage(message.Channel(), fmt.Sprintf("This channel is not subscribed to %s alerts.", worldName(worldID)))
+		} else {
+			client.SendMessage(message.Channel(), fmt.Sprintf("Unsubscribed from %s alerts!", worldName(worldID)))
+		}
+
+		p.RUnlock()
+		return
+	}
+
+	subscription := &alertSubscription{
+		ChannelID: message.Channel(),
+		Platform:  platform,
+		WorldID:   worldID,
+		GuildID:   channel.GuildID,
+	}
+
+	userID := message.UserID()
+	subscription.CreatedBy = &userID
+
+	if match := rolePattern.FindStringSubmatch(args["role"]); match != nil {
+		subscription.RoleID = &match[1]
+	}
+
+	err = p.repository.addAlertSubscription(subscription)
+	if err == nil {
+		p.watchWorldAlerts(platform, worldID)
+	}
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to subscribe to alerts.")
+	} else if subscription.RoleID != nil {
+		client.SendMessage(message.Channel(), fmt.Sprintf("Subscribed to %s alerts, mentioning <@&%s>!", worldName(worldID), *subscription.RoleID))
+	} else {
+		client.SendMessage(message.Channel(), fmt.Sprintf("Subscribed to %s alerts!", worldName(worldID)))
+	}
+
+	p.RUnlock()
+}
+
+func (p *planetsidetwoPlugin) sendAlertSubscriptions(client *discordgobot.DiscordClient, message discordgobot.Message, guildID string) {
+	subscriptions, err := p.repository.getGuildAlertSubscriptions(guildID)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Failed to get alert subscriptions.")
+		p.RUnlock()
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), "This server is not subscribed to any alerts.")
+		p.RUnlock()
+		return
+	}
+
+	lines := make([]string, len(subscriptions))
+	for i, subscription := range subscriptions {
+		lines[i] = fmt.Sprintf("<#%s>: %s", subscription.ChannelID, worldName(subscription.WorldID))
+		if subscription.RoleID != nil && *subscription.RoleID != "" {
+			lines[i] += fmt.Sprintf(" (<@&%s>)", *subscription.RoleID)
+		}
+	}
+
+	sort.Strings(lines)
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "Alert subscriptions",
+		Color:       p.embedColor(client, message.Channel(), 0),
+		Description: joinLinesWithinLimit(lines, embedDescriptionLimit),
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
@@ -0,0 +1,129 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultCacheTTL = 5 * time.Minute
+	// cacheStaleLifetime is how long an expired entry is kept around to be served when the API is down.
+	cacheStaleLifetime = 24 * time.Hour
+	cachePruneInterval = 10 * time.Minute
+)
+
+// voidwellCacheTTLs maps endpoint path fragments to how long their responses stay fresh.
+// The first matching fragment wins, so more specific paths come first.
+var voidwellCacheTTLs = []struct {
+	pathFragment string
+	ttl          time.Duration
+}{
+	{"/weaponinfo/", 6 * time.Hour},
+	{"/search/weapon/", 6 * time.Hour},
+	{"/weapon/", 10 * time.Minute},
+	{"/character/", 5 * time.Minute},
+	{"/outfit/", 30 * time.Minute},
+	{"/worldstate", time.Minute},
+}
+
+type cacheEntry struct {
+	value   json.RawMessage
+	expires time.Time
+}
+
+type responseCache struct {
+	sync.RWMutex
+	entries   map[string]*cacheEntry
+	lastPrune time.Time
+	hits      uint64
+	misses    uint64
+	staleHits uint64
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries:   make(map[string]*cacheEntry),
+		lastPrune: time.Now(),
+	}
+}
+
+// get returns the cached response for uri and whether it is still fresh.
+func (c *responseCache) get(uri string) (json.RawMessage, bool) {
+	c.RLock()
+	entry, ok := c.entries[uri]
+	c.RUnlock()
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) {
+		atomic.AddUint64(&c.misses, 1)
+		return entry.value, false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+	return entry.value, true
+}
+
+// getStale returns an entry regardless of its expiry, for use when the upstream API is unavailable.
+func (c *responseCache) getStale(uri string) (json.RawMessage, bool) {
+	c.RLock()
+	entry, ok := c.entries[uri]
+	c.RUnlock()
+
+	if !ok || time.Now().After(entry.expires.Add(cacheStaleLifetime)) {
+		return nil, false
+	}
+
+	atomic.AddUint64(&c.staleHits, 1)
+	return entry.value, true
+}
+
+func (c *responseCache) set(uri string, value json.RawMessage) {
+	now := time.Now()
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.entries[uri] = &cacheEntry{
+		value:   value,
+		expires: now.Add(cacheTTLForURI(uri)),
+	}
+
+	if now.Sub(c.lastPrune) > cachePruneInterval {
+		for key, entry := range c.entries {
+			if now.After(entry.expires.Add(cacheStaleLifetime)) {
+				delete(c.entries, key)
+			}
+		}
+		c.lastPrune = now
+	}
+}
+
+func (c *responseCache) stats() (hits uint64, misses uint64, staleHits uint64, size int) {
+	c.RLock()
+	size = len(c.entries)
+	c.RUnlock()
+
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses), atomic.LoadUint64(&c.staleHits), size
+}
+
+func cacheTTLForURI(uri string) time.Duration {
+	path := uri
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	for _, entry := range voidwellCacheTTLs {
+		if strings.Contains(path, entry.pathFragment) {
+			return entry.ttl
+		}
+	}
+
+	return defaultCacheTTL
+}
@@ -0,0 +1,102 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCacheTTLForURI(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected time.Duration
+	}{
+		{"https://api.voidwell.com/ps2/weaponinfo/byname/Gauss SAW", 6 * time.Hour},
+		{"https://api.voidwell.com/ps2/search/weapon/gauss", 6 * time.Hour},
+		{"https://api.voidwell.com/ps2/character/byname/Foo/weapon/Gauss SAW?platform=pc", 10 * time.Minute},
+		{"https://api.voidwell.com/ps2/character/byname/Foo?platform=pc", 5 * time.Minute},
+		{"https://api.voidwell.com/ps2/outfit/byalias/TIW?platform=pc", 30 * time.Minute},
+		{"https://api.voidwell.com/ps2/worldstate?platform=ps4eu", time.Minute},
+		// Only the path decides the TTL, not the query string.
+		{"https://api.voidwell.com/ps2/worldstate?q=/weaponinfo/", time.Minute},
+		{"https://api.voidwell.com/ps2/unknown", defaultCacheTTL},
+	}
+
+	for _, test := range tests {
+		if actual := cacheTTLForURI(test.uri); actual != test.expected {
+			t.Errorf("cacheTTLForURI(%q) = %s, want %s", test.uri, actual, test.expected)
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	const uri = "https://api.voidwell.com/ps2/character/byname/Foo?platform=pc"
+
+	tests := []struct {
+		name          string
+		expiresIn     time.Duration
+		expectedFresh bool
+		expectedStale bool
+	}{
+		{"fresh", time.Minute, true, true},
+		{"expired", -time.Minute, false, true},
+		{"expired within stale lifetime", -cacheStaleLifetime + time.Minute, false, true},
+		{"expired past stale lifetime", -cacheStaleLifetime - time.Minute, false, false},
+	}
+
+	for _, test := range tests {
+		cache := newResponseCache()
+		cache.set(uri, json.RawMessage(`{"name":"Foo"}`))
+		cache.entries[uri].expires = time.Now().Add(test.expiresIn)
+
+		value, fresh := cache.get(uri)
+		if fresh != test.expectedFresh {
+			t.Errorf("%s: get returned fresh %t, want %t", test.name, fresh, test.expectedFresh)
+		}
+
+		// Expired entries are still handed back so the caller can fall back to them.
+		if string(value) != `{"name":"Foo"}` {
+			t.Errorf("%s: get returned %s, want the cached value", test.name, value)
+		}
+
+		_, ok := cache.getStale(uri)
+		if ok != test.expectedStale {
+			t.Errorf("%s: getStale returned %t, want %t", test.name, ok, test.expectedStale)
+		}
+	}
+}
+
+func TestResponseCacheMiss(t *testing.T) {
+	cache := newResponseCache()
+
+	if value, fresh := cache.get("missing"); value != nil || fresh {
+		t.Errorf("get(%q) = %s, %t, want nothing", "missing", value, fresh)
+	}
+
+	if value, ok := cache.getStale("missing"); value != nil || ok {
+		t.Errorf("getStale(%q) = %s, %t, want nothing", "missing", value, ok)
+	}
+
+	if hits, misses, staleHits, size := cache.stats(); hits != 0 || misses != 1 || staleHits != 0 || size != 0 {
+		t.Errorf("stats() = %d, %d, %d, %d, want 0 hits, 1 miss, 0 stale hits and 0 entries", hits, misses, staleHits, size)
+	}
+}
+
+func TestResponseCachePrunesDeadEntries(t *testing.T) {
+	cache := newResponseCache()
+	cache.set("dead", json.RawMessage(`1`))
+	cache.set("stale", json.RawMessage(`2`))
+	cache.entries["dead"].expires = time.Now().Add(-cacheStaleLifetime - time.Minute)
+	cache.entries["stale"].expires = time.Now().Add(-time.Minute)
+	cache.lastPrune = time.Now().Add(-cachePruneInterval - time.Minute)
+
+	cache.set("fresh", json.RawMessage(`3`))
+
+	if _, ok := cache.entries["dead"]; ok {
+		t.Error("set did not prune an entry past its stale lifetime")
+	}
+
+	if _, ok := cache.entries["stale"]; !ok {
+		t.Error("set pruned an entry that could still be served stale")
+	}
+}
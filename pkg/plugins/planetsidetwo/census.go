@@ -0,0 +1,653 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CENSUS_API_URI = "https://census.daybreakgames.com/"
+
+// censusWeaponPageSize is how many weapons are requested at a time when listing them all.
+const censusWeaponPageSize = 1000
+
+var censusHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+var censusNamespaces = map[string]string{
+	"pc":    "ps2:v2",
+	"ps4us": "ps2ps4us:v2",
+	"ps4eu": "ps2ps4eu:v2",
+}
+
+// censusDataSource queries the Daybreak Census API directly. Census does not
+// compute the derived stats Voidwell provides (HSR, siege level, IVI, outfit
+// activity and weapon accuracy states), so those are left empty.
+type censusDataSource struct {
+	serviceID string
+}
+
+func newCensusDataSource() *censusDataSource {
+	return &censusDataSource{
+		serviceID: censusServiceID(),
+	}
+}
+
+func censusServiceID() string {
+	if serviceID := os.Getenv("CensusServiceId"); serviceID != "" {
+		return serviceID
+	}
+
+	return "example"
+}
+
+func (s *censusDataSource) Name() string {
+	return dataSourceCensus
+}
+
+type censusLocalizedString struct {
+	En string `json:"en"`
+}
+
+type censusMetagameEvent struct {
+	MetagameEventID string                `json:"metagame_event_id"`
+	Name            censusLocalizedString `json:"name"`
+	Description     censusLocalizedString `json:"description"`
+}
+
+type censusCharacter struct {
+	CharacterID string `json:"character_id"`
+	Name        struct {
+		First string `json:"first"`
+	} `json:"name"`
+	FactionID string `json:"faction_id"`
+	Times     struct {
+		LastSave      string `json:"last_save"`
+		MinutesPlayed string `json:"minutes_played"`
+	} `json:"times"`
+	BattleRank struct {
+		Value string `json:"value"`
+	} `json:"battle_rank"`
+	PrestigeLevel string `json:"prestige_level"`
+	WorldID       string `json:"world_id"`
+	Outfit        *struct {
+		Name  string `json:"name"`
+		Alias string `json:"alias"`
+	} `json:"outfit"`
+	Stats *struct {
+		StatHistory []struct {
+			StatName string `json:"stat_name"`
+			AllTime  string `json:"all_time"`
+		} `json:"stat_history"`
+	} `json:"stats"`
+	Faction *censusFaction `json:"faction"`
+}
+
+type censusFaction struct {
+	Name    censusLocalizedString `json:"name"`
+	ImageID string                `json:"image_id"`
+}
+
+type censusOutfit struct {
+	OutfitID    string `json:"outfit_id"`
+	Name        string `json:"name"`
+	Alias       string `json:"alias"`
+	MemberCount string `json:"member_count"`
+	Leader      *struct {
+		Name struct {
+			First string `json:"first"`
+		} `json:"name"`
+		FactionID string         `json:"faction_id"`
+		Faction   *censusFaction `json:"faction"`
+		World     *struct {
+			WorldID string `json:"world_id"`
+		} `json:"world"`
+	} `json:"leader"`
+}
+
+type censusOutfitMember struct {
+	CharacterID     string `json:"character_id"`
+	MemberSinceDate string `json:"member_since"`
+	Rank            string `json:"rank"`
+	RankOrdinal     string `json:"rank_ordinal"`
+	Character       *struct {
+		Name struct {
+			First string `json:"first"`
+		} `json:"name"`
+		BattleRank struct {
+			Value string `json:"value"`
+		} `json:"battle_rank"`
+		PrestigeLevel string `json:"prestige_level"`
+		Times         struct {
+			LastLogin string `json:"last_login"`
+		} `json:"times"`
+	} `json:"character"`
+}
+
+type censusOnlineOutfitMember struct {
+	CharacterID string `json:"character_id"`
+	Rank        string `json:"rank"`
+	RankOrdinal string `json:"rank_ordinal"`
+	Online      *struct {
+		OnlineStatus string `json:"online_status"`
+	} `json:"online"`
+	Character *struct {
+		Name struct {
+			First string `json:"first"`
+		} `json:"name"`
+		BattleRank struct {
+			Value string `json:"value"`
+		} `json:"battle_rank"`
+		PrestigeLevel string `json:"prestige_level"`
+		Profile       *struct {
+			Name censusLocalizedString `json:"name"`
+		} `json:"profile"`
+	} `json:"character"`
+}
+
+type censusMap struct {
+	ZoneID  string `json:"ZoneId"`
+	Regions struct {
+		Row []struct {
+			RowData struct {
+				RegionID  string `json:"RegionId"`
+				FactionID string `json:"FactionId"`
+			} `json:"RowData"`
+		} `json:"Row"`
+	} `json:"Regions"`
+}
+
+type censusItem struct {
+	ItemID          string                `json:"item_id"`
+	Name            censusLocalizedString `json:"name"`
+	Description     censusLocalizedString `json:"description"`
+	FactionID       string                `json:"faction_id"`
+	ImageID         string                `json:"image_id"`
+	MaxStackSize    string                `json:"max_stack_size"`
+	IsVehicleWeapon string                `json:"is_vehicle_weapon"`
+	Category        *struct {
+		Name censusLocalizedString `json:"name"`
+	} `json:"category"`
+	Datasheet *struct {
+		DamageMin  string                `json:"damage_min"`
+		DamageMax  string                `json:"damage_max"`
+		FireRateMs string                `json:"fire_rate_ms"`
+		ReloadMs   string                `json:"reload_ms"`
+		ClipSize   string                `json:"clip_size"`
+		Capacity   string                `json:"capacity"`
+		Range      censusLocalizedString `json:"range"`
+	} `json:"datasheet"`
+}
+
+func (s *censusDataSource) GetCharacter(characterName string, platform string) (*PlanetsideCharacter, error) {
+	query := url.Values{}
+	query.Set("name.first_lower", strings.ToLower(characterName))
+
+	return s.getCharacter(query, fmt.Sprintf("character named %s on %s", characterName, platformDisplayName(platform)), platform)
+}
+
+func (s *censusDataSource) GetCharacterByID(characterID string, platform string) (*PlanetsideCharacter, error) {
+	query := url.Values{}
+	query.Set("character_id", characterID)
+
+	return s.getCharacter(query, fmt.Sprintf("character with ID %s on %s", characterID, platformDisplayName(platform)), platform)
+}
+
+func (s *censusDataSource) getCharacter(query url.Values, subject string, platform string) (*PlanetsideCharacter, error) {
+	query.Set("c:resolve", "outfit(name,alias),world,stat_history")
+	query.Set("c:join", "faction^inject_at:faction^show:name'image_id")
+
+	var records []censusCharacter
+	err := s.get(platform, "character", query, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &censusError{Kind: censusNotFound, Subject: subject}
+	}
+
+	record := records[0]
+
+	character := &PlanetsideCharacter{
+		CharacterId:          record.CharacterID,
+		World:                worldName(record.WorldID),
+		Name:                 record.Name.First,
+		LastSaved:            time.Unix(int64(censusAtoi(record.Times.LastSave)), 0).UTC().Format(time.RFC3339),
+		FactionId:            censusAtoi(record.FactionID),
+		BattleRank:           censusAtoi(record.BattleRank.Value),
+		TotalPlayTimeMinutes: censusAtoi(record.Times.MinutesPlayed),
+		Prestige:             censusAtoi(record.PrestigeLevel),
+		Platform:             platform,
+	}
+
+	if record.Faction != nil {
+		character.FactionName = record.Faction.Name.En
+		character.FactionImageId = censusAtoi(record.Faction.ImageID)
+	}
+
+	if record.Outfit != nil {
+		character.OutfitName = record.Outfit.Name
+		character.OutfitAlias = record.Outfit.Alias
+	}
+
+	if record.Stats != nil {
+		for _, stat := range record.Stats.StatHistory {
+			switch stat.StatName {
+			case "kills":
+				character.Kills = censusAtoi(stat.AllTime)
+			case "deaths":
+				character.Deaths = censusAtoi(stat.AllTime)
+			case "score":
+				character.Score = censusAtoi(stat.AllTime)
+			case "time":
+				character.PlayTime = censusAtoi(stat.AllTime)
+			}
+		}
+	}
+
+	if character.Deaths > 0 {
+		character.KillDeathRatio = float32(character.Kills) / float32(character.Deaths)
+	}
+
+	if character.PlayTime > 0 {
+		character.KillsPerHour = float32(character.Kills) / (float32(character.PlayTime) / 3600)
+	}
+
+	if character.TotalPlayTimeMinutes > 0 {
+		character.TotalKillsPerHour = float32(character.Kills) / (float32(character.TotalPlayTimeMinutes) / 60)
+	}
+
+	return character, nil
+}
+
+func (s *censusDataSource) GetCharacterWeapon(characterName string, weaponName string, platform string) (*PlanetsideCharacterWeapon, error) {
+	return nil, &censusError{Kind: censusUnsupported, Subject: "character weapon stats"}
+}
+
+func (s *censusDataSource) GetOutfit(outfitAlias string, platform string) (*PlanetsideOutfit, error) {
+	query := url.Values{}
+	query.Set("alias_lower", strings.ToLower(outfitAlias))
+	query.Set("c:join", "character^on:leader_character_id^to:character_id^inject_at:leader^show:name.first'faction_id(faction^inject_at:faction^show:name'image_id,characters_world^inject_at:world)")
+
+	var records []censusOutfit
+	err := s.get(platform, "outfit", query, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &censusError{Kind: censusNotFound, Subject: fmt.Sprintf("outfit tagged [%s] on %s", outfitAlias, platformDisplayName(platform))}
+	}
+
+	record := records[0]
+
+	outfit := &PlanetsideOutfit{
+		OutfitId:    record.OutfitID,
+		Name:        record.Name,
+		Alias:       record.Alias,
+		MemberCount: censusAtoi(record.MemberCount),
+	}
+
+	if record.Leader != nil {
+		outfit.LeaderName = record.Leader.Name.First
+		outfit.FactionId = censusAtoi(record.Leader.FactionID)
+
+		if record.Leader.Faction != nil {
+			outfit.FactionName = record.Leader.Faction.Name.En
+			outfit.FactionImageId = censusAtoi(record.Leader.Faction.ImageID)
+		}
+
+		if record.Leader.World != nil {
+			outfit.WorldName = worldName(record.Leader.World.WorldID)
+		}
+	}
+
+	return outfit, nil
+}
+
+func (s *censusDataSource) GetOutfitMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	query := url.Values{}
+	query.Set("outfit_id", outfitID)
+	query.Set("c:limit", "5000")
+	query.Set("c:join", "character^on:character_id^inject_at:character^show:name.first'battle_rank.value'prestige_level'times.last_login")
+
+	var records []censusOutfitMember
+	err := s.get(platform, "outfit_member", query, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*PlanetsideOutfitMember, 0, len(records))
+	for _, record := range records {
+		member := &PlanetsideOutfitMember{
+			CharacterId: record.CharacterID,
+			Rank:        record.Rank,
+			RankOrdinal: censusAtoi(record.RankOrdinal),
+			MemberSince: time.Unix(int64(censusAtoi(record.MemberSinceDate)), 0).UTC().Format(time.RFC3339),
+		}
+
+		if record.Character != nil {
+			member.Name = record.Character.Name.First
+			member.BattleRank = censusAtoi(record.Character.BattleRank.Value)
+			member.Prestige = censusAtoi(record.Character.PrestigeLevel)
+			member.LastLogin = time.Unix(int64(censusAtoi(record.Character.Times.LastLogin)), 0).UTC().Format(time.RFC3339)
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// GetOutfitOnlineMembers returns the members whose online status is set. The class is the one
+// the character last played, which Census updates as they switch.
+func (s *censusDataSource) GetOutfitOnlineMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	query := url.Values{}
+	query.Set("outfit_id", outfitID)
+	query.Set("c:limit", "5000")
+	query.Set("c:join", "characters_online_status^on:character_id^inject_at:online,character^on:character_id^inject_at:character^show:name.first'battle_rank.value'prestige_level'profile_id(profile^inject_at:profile^show:name.en)")
+
+	var records []censusOnlineOutfitMember
+	err := s.get(platform, "outfit_member", query, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*PlanetsideOutfitMember, 0)
+	for _, record := range records {
+		if record.Online == nil || record.Online.OnlineStatus == "" || record.Online.OnlineStatus == "0" {
+			continue
+		}
+
+		member := &PlanetsideOutfitMember{
+			CharacterId: record.CharacterID,
+			Rank:        record.Rank,
+			RankOrdinal: censusAtoi(record.RankOrdinal),
+			Online:      true,
+		}
+
+		if record.Character != nil {
+			member.Name = record.Character.Name.First
+			member.BattleRank = censusAtoi(record.Character.BattleRank.Value)
+			member.Prestige = censusAtoi(record.Character.PrestigeLevel)
+
+			if record.Character.Profile != nil {
+				member.ClassName = record.Character.Profile.Name.En
+			}
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// GetWorldStates reports territory from region ownership on each world of the platform. Census
+// does not publish population, so it is left empty.
+func (s *censusDataSource) GetWorldStates(platform string) ([]*PlanetsideWorldState, error) {
+	zoneIDs := make([]string, 0)
+	for _, continentID := range planetsideMetadata.continentIDs() {
+		zoneIDs = append(zoneIDs, strconv.Itoa(continentID))
+	}
+
+	worldIDs := planetsideMetadata.platformWorldIDs(platform)
+
+	worlds := make([]*PlanetsideWorldState, 0, len(worldIDs))
+	for _, worldID := range worldIDs {
+		query := url.Values{}
+		query.Set("world_id", worldID)
+		query.Set("zone_ids", strings.Join(zoneIDs, ","))
+
+		var records []censusMap
+		err := s.get(platform, "map", query, &records)
+		if err != nil {
+			return nil, err
+		}
+
+		world := &PlanetsideWorldState{
+			WorldID:    worldID,
+			Name:       worldName(worldID),
+			Continents: make([]*PlanetsideContinentState, 0, len(records)),
+		}
+
+		for _, record := range records {
+			world.Continents = append(world.Continents, newCensusContinentState(record))
+		}
+
+		worlds = append(worlds, world)
+	}
+
+	return worlds, nil
+}
+
+// newCensusContinentState derives territory from the share of regions each faction holds. A
+// continent held entirely by one faction is locked.
+func newCensusContinentState(record censusMap) *PlanetsideContinentState {
+	regions := make(map[int]int)
+	total := 0
+	for _, row := range record.Regions.Row {
+		factionID := censusAtoi(row.RowData.FactionID)
+		if factionID == 0 {
+			continue
+		}
+		regions[factionID]++
+		total++
+	}
+
+	continent := &PlanetsideContinentState{
+		ZoneID:    record.ZoneID,
+		Name:      zoneName(record.ZoneID),
+		IsOpen:    len(regions) > 1,
+		Territory: make(map[int]float64, len(regions)),
+	}
+
+	for factionID, count := range regions {
+		continent.Territory[factionID] = float64(count) / float64(total) * 100
+	}
+
+	return continent
+}
+
+func (s *censusDataSource) GetWeapon(weaponName string) (*PlanetsideWeapon, error) {
+	query := url.Values{}
+	query.Set("name.en", weaponName)
+	query.Set("item_type_id", "26")
+	query.Set("c:case", "false")
+	query.Add("c:join", "weapon_datasheet^inject_at:datasheet")
+	query.Add("c:join", "item_category^inject_at:category^show:name")
+
+	var records []censusItem
+	err := s.get("pc", "item", query, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &censusError{Kind: censusNotFound, Subject: fmt.Sprintf("weapon named %s", weaponName)}
+	}
+
+	return records[0].toWeapon(), nil
+}
+
+// SearchWeapons finds weapons whose name contains the query. When nothing matches, it
+// widens the search to the first letters of the query so typos still produce candidates.
+func (s *censusDataSource) SearchWeapons(query string) ([]*PlanetsideWeaponSearchResult, error) {
+	results, err := s.searchWeapons(query)
+	if err != nil || len(results) > 0 {
+		return results, err
+	}
+
+	fields := strings.Fields(query)
+	if len(fields) == 0 || len(fields[0]) <= 3 {
+		return results, nil
+	}
+
+	return s.searchWeapons(fields[0][:3])
+}
+
+func (s *censusDataSource) searchWeapons(query string) ([]*PlanetsideWeaponSearchResult, error) {
+	search := url.Values{}
+	search.Set("name.en", "*"+query)
+	search.Set("item_type_id", "26")
+	search.Set("c:case", "false")
+	search.Set("c:limit", "100")
+	search.Set("c:show", "item_id,name.en,faction_id,item_category_id")
+	search.Set("c:join", "item_category^inject_at:category^show:name")
+
+	var records []censusItem
+	err := s.get("pc", "item", search, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	return censusWeaponSearchResults(records), nil
+}
+
+// ListWeapons lists every weapon item, a page at a time.
+func (s *censusDataSource) ListWeapons() ([]*PlanetsideWeaponSearchResult, error) {
+	results := make([]*PlanetsideWeaponSearchResult, 0)
+
+	for start := 0; ; start += censusWeaponPageSize {
+		query := url.Values{}
+		query.Set("item_type_id", "26")
+		query.Set("c:start", strconv.Itoa(start))
+		query.Set("c:limit", strconv.Itoa(censusWeaponPageSize))
+		query.Set("c:show", "item_id,name.en,faction_id,item_category_id")
+		query.Set("c:join", "item_category^inject_at:category^show:name")
+
+		var records []censusItem
+		err := s.get("pc", "item", query, &records)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, censusWeaponSearchResults(records)...)
+
+		if len(records) < censusWeaponPageSize {
+			return results, nil
+		}
+	}
+}
+
+func censusWeaponSearchResults(records []censusItem) []*PlanetsideWeaponSearchResult {
+	results := make([]*PlanetsideWeaponSearchResult, 0, len(records))
+	for _, record := range records {
+		result := &PlanetsideWeaponSearchResult{
+			ItemID:    censusAtoi(record.ItemID),
+			Name:      record.Name.En,
+			FactionID: censusAtoi(record.FactionID),
+		}
+
+		if record.Category != nil {
+			result.Category = record.Category.Name.En
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func (i *censusItem) toWeapon() *PlanetsideWeapon {
+	weapon := &PlanetsideWeapon{
+		Name:            i.Name.En,
+		ItemID:          censusAtoi(i.ItemID),
+		FactionID:       censusAtoi(i.FactionID),
+		ImageID:         censusAtoi(i.ImageID),
+		Description:     i.Description.En,
+		MaxStackSize:    censusAtoi(i.MaxStackSize),
+		IsVehicleWeapon: i.IsVehicleWeapon == "1",
+	}
+
+	if i.Category != nil {
+		weapon.Category = i.Category.Name.En
+	}
+
+	if i.Datasheet != nil {
+		weapon.Range = i.Datasheet.Range.En
+		weapon.FireRateMs = censusAtoi(i.Datasheet.FireRateMs)
+		weapon.ClipSize = censusAtoi(i.Datasheet.ClipSize)
+		weapon.Capacity = censusAtoi(i.Datasheet.Capacity)
+		weapon.MinDamage = censusAtoi(i.Datasheet.DamageMin)
+		weapon.MaxDamage = censusAtoi(i.Datasheet.DamageMax)
+		weapon.MinReloadSpeed = censusAtoi(i.Datasheet.ReloadMs)
+		weapon.MaxReloadSpeed = weapon.MinReloadSpeed
+	}
+
+	return weapon
+}
+
+func (s *censusDataSource) getMetagameEvent(metagameEventID string, platform string) (*censusMetagameEvent, error) {
+	query := url.Values{}
+	query.Set("metagame_event_id", metagameEventID)
+
+	var records []censusMetagameEvent
+	err := s.get(platform, "metagame_event", query, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &censusError{Kind: censusNotFound, Subject: fmt.Sprintf("metagame event with ID %s", metagameEventID)}
+	}
+
+	return &records[0], nil
+}
+
+// get runs a Census query against the collection and decodes its '<collection>_list' array into result.
+func (s *censusDataSource) get(platform string, collection string, query url.Values, result interface{}) error {
+	namespace, ok := censusNamespaces[platform]
+	if !ok {
+		return fmt.Errorf("Unknown platform '%s'", platform)
+	}
+
+	uri := fmt.Sprintf("%ss:%s/get/%s/%s/?%s", CENSUS_API_URI, s.serviceID, namespace, collection, query.Encode())
+
+	resp, err := censusHTTPClient.Get(uri)
+	if err != nil {
+		return newCensusRequestError(uri, err)
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return newCensusRequestError(uri, err)
+	}
+
+	if resp.StatusCode != 200 {
+		return newCensusStatusError(uri, resp.StatusCode, body)
+	}
+
+	var response map[string]json.RawMessage
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		log.Printf("Failed to unmarshal Census response for %v: %v", uri, err)
+		return &censusError{Kind: censusMalformed}
+	}
+
+	// Census reports most failures, such as an overloaded or unknown service, as a 200 with an error body.
+	list, ok := response[collection+"_list"]
+	if !ok {
+		return newCensusStatusError(uri, resp.StatusCode, body)
+	}
+
+	err = json.Unmarshal(list, result)
+	if err != nil {
+		log.Printf("Failed to unmarshal Census %s list for %v: %v", collection, uri, err)
+		return &censusError{Kind: censusMalformed}
+	}
+
+	return nil
+}
+
+func censusAtoi(value string) int {
+	i, _ := strconv.Atoi(value)
+	return i
+}
@@ -0,0 +1,74 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+)
+
+type censusErrorKind int
+
+const (
+	censusNotFound censusErrorKind = iota
+	censusUnsupported
+	censusUnavailable
+	censusTimeout
+	censusMalformed
+)
+
+// censusError describes a failed Census call. Like voidwellError, its message is meant for the
+// channel, so the response body and underlying error are only logged.
+type censusError struct {
+	Kind       censusErrorKind
+	StatusCode int
+	// Subject names what was looked up, e.g. "character named Foo on PS4 EU", or for
+	// censusUnsupported, what Census does not provide.
+	Subject string
+}
+
+func (e *censusError) Error() string {
+	switch e.Kind {
+	case censusNotFound:
+		if e.Subject != "" {
+			return fmt.Sprintf("No %s.", e.Subject)
+		}
+		return "Nothing was found."
+	case censusUnsupported:
+		return fmt.Sprintf("Census does not provide %s.", e.Subject)
+	case censusTimeout:
+		return "Census took too long to respond. Try again later."
+	case censusMalformed:
+		return "Census sent a response the bot could not read."
+	}
+
+	return "Census is having trouble right now. Try again later."
+}
+
+// unavailable reports whether Census failed rather than the lookup, mirroring voidwellError.
+// Lookups Census does not support count, so another data source can answer them.
+func (e *censusError) unavailable() bool {
+	return e.Kind != censusNotFound
+}
+
+// newCensusStatusError classifies an unsuccessful response and logs its body.
+func newCensusStatusError(uri string, statusCode int, body []byte) *censusError {
+	log.Printf("Census returned %d for %v: %s", statusCode, uri, body)
+
+	if statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout {
+		return &censusError{Kind: censusTimeout, StatusCode: statusCode}
+	}
+
+	return &censusError{Kind: censusUnavailable, StatusCode: statusCode}
+}
+
+// newCensusRequestError classifies a request that got no response and logs the underlying error.
+func newCensusRequestError(uri string, err error) *censusError {
+	log.Printf("Census request for %v failed: %v", uri, err)
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return &censusError{Kind: censusTimeout}
+	}
+
+	return &censusError{Kind: censusUnavailable}
+}
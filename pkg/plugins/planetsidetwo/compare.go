@@ -0,0 +1,191 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+type characterComparisonRow struct {
+	label  string
+	first  float64
+	second float64
+	format func(character *PlanetsideCharacter) string
+	// neutral rows have no better value, and lowerIsBetter rows mark the lower value.
+	neutral       bool
+	lowerIsBetter bool
+}
+
+func (p *planetsidetwoPlugin) runCharacterCompareCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	names := []string{args["firstCharacterName"], args["secondCharacterName"]}
+	characters := make([]*PlanetsideCharacter, len(names))
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			characters[i], errs[i] = p.dataSource.GetCharacter(name, platform)
+		}(i, name)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			p.RLock()
+			client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+			p.RUnlock()
+			return
+		}
+	}
+
+	first, second := characters[0], characters[1]
+
+	go p.trackCharacter(first)
+	go p.trackCharacter(second)
+
+	rows := []characterComparisonRow{
+		{
+			label:  "KDR",
+			first:  float64(first.KillDeathRatio),
+			second: float64(second.KillDeathRatio),
+			format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", c.KillDeathRatio) },
+		},
+		{
+			label:  "HSR",
+			first:  float64(first.HeadshotRatio),
+			second: float64(second.HeadshotRatio),
+			format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f%%", c.HeadshotRatio*100) },
+		},
+		{
+			label:  "KpH",
+			first:  float64(first.KillsPerHour),
+			second: float64(second.KillsPerHour),
+			format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", c.KillsPerHour) },
+		},
+		{
+			label:         "DpH",
+			first:         deathsPerHour(first),
+			second:        deathsPerHour(second),
+			format:        func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", deathsPerHour(c)) },
+			lowerIsBetter: true,
+		},
+		{
+			label:  "IVI Score",
+			first:  float64(first.IVIScore),
+			second: float64(second.IVIScore),
+			format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%d", c.IVIScore) },
+		},
+		{
+			label:  "IVI KDR",
+			first:  float64(first.IVIKillDeathRatio),
+			second: float64(second.IVIKillDeathRatio),
+			format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", c.IVIKillDeathRatio) },
+		},
+		{
+			label:   "Siege Level",
+			first:   float64(first.SiegeLevel),
+			second:  float64(second.SiegeLevel),
+			format:  func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.1f", c.SiegeLevel) },
+			neutral: true,
+		},
+		{
+			label:  "Battle Rank",
+			first:  float64(first.Prestige*100 + first.BattleRank),
+			second: float64(second.Prestige*100 + second.BattleRank),
+			format: formatBattleRank,
+		},
+		{
+			label:  "Play Time",
+			first:  float64(first.PlayTime),
+			second: float64(second.PlayTime),
+			format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.1f Hours", float32(c.PlayTime)/3600.0) },
+		},
+	}
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(rows))
+	for _, row := range rows {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   row.label,
+			Value:  formatComparisonRow(row, first, second),
+			Inline: false,
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: first.Name + " vs " + second.Name,
+		},
+		Color:  p.embedColor(client, message.Channel(), 0),
+		Fields: fields,
+		Footer: createStaleFooter(first.Stale || second.Stale),
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
+
+// formatComparisonRow renders both values with the better one in bold and checked,
+// followed by the first character's percentage difference from the second.
+func formatComparisonRow(row characterComparisonRow, first *PlanetsideCharacter, second *PlanetsideCharacter) string {
+	firstValue, secondValue := row.format(first), row.format(second)
+
+	if !row.neutral {
+		firstBetter, secondBetter := row.first > row.second, row.second > row.first
+		if row.lowerIsBetter {
+			firstBetter, secondBetter = secondBetter, firstBetter
+		}
+
+		if firstBetter {
+			firstValue = "**" + firstValue + "** ✓"
+		} else if secondBetter {
+			secondValue = "**" + secondValue + "** ✓"
+		}
+	}
+
+	return fmt.Sprintf("%s: %s | %s: %s | Δ %s", first.Name, firstValue, second.Name, secondValue, formatPercentDifference(row.first, row.second))
+}
+
+func formatPercentDifference(value float64, baseline float64) string {
+	if baseline == 0 {
+		if value == 0 {
+			return "0%"
+		}
+		return "n/a"
+	}
+
+	difference := (value - baseline) / math.Abs(baseline) * 100
+
+	return fmt.Sprintf("%+0.1f%%", difference)
+}
+
+func deathsPerHour(character *PlanetsideCharacter) float64 {
+	if character.PlayTime <= 0 {
+		return 0
+	}
+
+	return float64(character.Deaths) / (float64(character.PlayTime) / 3600)
+}
+
+func formatBattleRank(character *PlanetsideCharacter) string {
+	if character.Prestige > 0 {
+		return fmt.Sprintf("%d (ASP %d)", character.BattleRank, character.Prestige)
+	}
+
+	return fmt.Sprintf("%d", character.BattleRank)
+}
@@ -0,0 +1,88 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFormatComparisonRow(t *testing.T) {
+	formatKDR := func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", c.KillDeathRatio) }
+
+	tests := []struct {
+		name          string
+		first         float32
+		second        float32
+		neutral       bool
+		lowerIsBetter bool
+		expected      string
+	}{
+		{"first better", 2, 1, false, false, "Foo: **2.00** ✓ | Bar: 1.00 | Δ +100.0%"},
+		{"second better", 1, 2, false, false, "Foo: 1.00 | Bar: **2.00** ✓ | Δ -50.0%"},
+		{"tie", 1.5, 1.5, false, false, "Foo: 1.50 | Bar: 1.50 | Δ +0.0%"},
+		{"neutral", 2, 1, true, false, "Foo: 2.00 | Bar: 1.00 | Δ +100.0%"},
+		{"lower is better, first lower", 1, 2, false, true, "Foo: **1.00** ✓ | Bar: 2.00 | Δ -50.0%"},
+		{"lower is better, second lower", 2, 1, false, true, "Foo: 2.00 | Bar: **1.00** ✓ | Δ +100.0%"},
+		{"lower is better, tie", 1, 1, false, true, "Foo: 1.00 | Bar: 1.00 | Δ +0.0%"},
+		{"zero base value", 1, 0, false, false, "Foo: **1.00** ✓ | Bar: 0.00 | Δ n/a"},
+		{"both zero", 0, 0, false, false, "Foo: 0.00 | Bar: 0.00 | Δ 0%"},
+	}
+
+	for _, test := range tests {
+		first := &PlanetsideCharacter{Name: "Foo", KillDeathRatio: test.first}
+		second := &PlanetsideCharacter{Name: "Bar", KillDeathRatio: test.second}
+		row := characterComparisonRow{
+			label:         "KDR",
+			first:         float64(test.first),
+			second:        float64(test.second),
+			format:        formatKDR,
+			neutral:       test.neutral,
+			lowerIsBetter: test.lowerIsBetter,
+		}
+
+		if actual := formatComparisonRow(row, first, second); actual != test.expected {
+			t.Errorf("%s: formatComparisonRow = %q, want %q", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestFormatPercentDifference(t *testing.T) {
+	tests := []struct {
+		value    float64
+		baseline float64
+		expected string
+	}{
+		{150, 100, "+50.0%"},
+		{50, 100, "-50.0%"},
+		{100, 100, "+0.0%"},
+		{0, 100, "-100.0%"},
+		{-1, -2, "+50.0%"},
+		{5, 0, "n/a"},
+		{-5, 0, "n/a"},
+		{0, 0, "0%"},
+	}
+
+	for _, test := range tests {
+		if actual := formatPercentDifference(test.value, test.baseline); actual != test.expected {
+			t.Errorf("formatPercentDifference(%v, %v) = %q, want %q", test.value, test.baseline, actual, test.expected)
+		}
+	}
+}
+
+func TestDeathsPerHour(t *testing.T) {
+	tests := []struct {
+		deaths   int
+		playTime int
+		expected float64
+	}{
+		{30, 7200, 15},
+		{0, 3600, 0},
+		{10, 0, 0},
+	}
+
+	for _, test := range tests {
+		character := &PlanetsideCharacter{Deaths: test.deaths, PlayTime: test.playTime}
+		if actual := deathsPerHour(character); actual != test.expected {
+			t.Errorf("deathsPerHour(%d deaths in %ds) = %v, want %v", test.deaths, test.playTime, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	VOIDWELL_URI         = "https://voidwell.com/"
+	VOIDWELL_API_URI     = "https://voidwell.com/api/"
+	VOIDWELL_TOKEN_URI   = "https://auth.voidwell.com/connect/token"
+	CENSUS_IMAGEBASE_URI = "http://census.daybreakgames.com/files/ps2/images/static/"
+)
+
+var defaultVoidwellScopes = []string{"voidwell-daybreakgames", "voidwell-api"}
+
+// serviceConfig holds the endpoints of the services the plugin talks to, so the bot can be
+// pointed at a self-hosted or staging Voidwell, or at a fake server when testing.
+type serviceConfig struct {
+	// VoidwellURI is the public site linked to from embeds.
+	VoidwellURI          string
+	VoidwellAPIURI       string
+	VoidwellTokenURI     string
+	VoidwellScopes       []string
+	VoidwellClientID     string
+	VoidwellClientSecret string
+	CensusImageBaseURI   string
+}
+
+// loadServiceConfig reads the endpoints from the environment, using the public services for any that are not set.
+func loadServiceConfig() *serviceConfig {
+	config := &serviceConfig{
+		VoidwellURI:          withTrailingSlash(envOrDefault("VoidwellURI", VOIDWELL_URI)),
+		VoidwellAPIURI:       withTrailingSlash(envOrDefault("VoidwellAPIURI", VOIDWELL_API_URI)),
+		VoidwellTokenURI:     envOrDefault("VoidwellTokenURI", VOIDWELL_TOKEN_URI),
+		VoidwellScopes:       defaultVoidwellScopes,
+		VoidwellClientID:     os.Getenv("VoidwellClientId"),
+		VoidwellClientSecret: os.Getenv("VoidwellClientSecret"),
+		CensusImageBaseURI:   withTrailingSlash(envOrDefault("CensusImageBaseURI", CENSUS_IMAGEBASE_URI)),
+	}
+
+	if scopes := os.Getenv("VoidwellScopes"); scopes != "" {
+		config.VoidwellScopes = strings.Fields(strings.Replace(scopes, ",", " ", -1))
+	}
+
+	return config
+}
+
+// voidwellAPI returns the API endpoint for path, e.g. "ps2/worldstate?platform=pc".
+func (c *serviceConfig) voidwellAPI(format string, args ...interface{}) string {
+	return c.VoidwellAPIURI + fmt.Sprintf(format, args...)
+}
+
+// voidwellLink returns the public page for path, e.g. "ps2/player/5428010618015189713".
+func (c *serviceConfig) voidwellLink(format string, args ...interface{}) string {
+	return c.VoidwellURI + fmt.Sprintf(format, args...)
+}
+
+func (c *serviceConfig) censusImage(imageID int) string {
+	return c.CensusImageBaseURI + fmt.Sprintf("%v", imageID) + ".png"
+}
+
+func envOrDefault(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+func withTrailingSlash(uri string) string {
+	if strings.HasSuffix(uri, "/") {
+		return uri
+	}
+
+	return uri + "/"
+}
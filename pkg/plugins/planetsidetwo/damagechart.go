@@ -0,0 +1,325 @@
+package planetsidetwoplugin
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	damageChartFileName = "damage.png"
+
+	chartWidth        = 640
+	chartHeight       = 320
+	chartMarginLeft   = 52
+	chartMarginRight  = 28
+	chartMarginTop    = 16
+	chartMarginBottom = 36
+	chartFontScale    = 2
+	chartLineWidth    = 2
+	chartMaxTicks     = 10
+)
+
+var (
+	chartBackground = color.RGBA{0x2F, 0x31, 0x36, 0xFF}
+	chartGrid       = color.RGBA{0x40, 0x44, 0x4B, 0xFF}
+	chartForeground = color.RGBA{0xB9, 0xBB, 0xBE, 0xFF}
+)
+
+// chartPalette colors the series of a chart in order. Overlaid weapons take two colors each,
+// one for direct and one for indirect damage.
+var chartPalette = []struct {
+	name  string
+	color color.RGBA
+}{
+	{"orange", color.RGBA{0xFF, 0xA7, 0x26, 0xFF}},
+	{"blue", color.RGBA{0x42, 0xA5, 0xF5, 0xFF}},
+	{"green", color.RGBA{0x66, 0xBB, 0x6A, 0xFF}},
+	{"pink", color.RGBA{0xEC, 0x40, 0x7A, 0xFF}},
+	{"yellow", color.RGBA{0xFF, 0xEE, 0x58, 0xFF}},
+	{"purple", color.RGBA{0xAB, 0x47, 0xBC, 0xFF}},
+}
+
+var chartTickSteps = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// chartGlyphs is a 3x5 bitmap font covering the characters used in axis labels.
+var chartGlyphs = map[rune][5]string{
+	'0': {"111", "101", "101", "101", "111"},
+	'1': {"010", "110", "010", "010", "111"},
+	'2': {"111", "001", "111", "100", "111"},
+	'3': {"111", "001", "111", "001", "111"},
+	'4': {"101", "101", "111", "001", "001"},
+	'5': {"111", "100", "111", "001", "111"},
+	'6': {"111", "100", "111", "101", "111"},
+	'7': {"111", "001", "001", "001", "001"},
+	'8': {"111", "101", "111", "101", "111"},
+	'9': {"111", "101", "111", "001", "111"},
+	'm': {"000", "000", "110", "111", "101"},
+}
+
+// chartSeries is one line on a chart. value reports false for ranges the series does not cover.
+type chartSeries struct {
+	color color.RGBA
+	value func(x float64) (float64, bool)
+}
+
+// renderDamageChart draws damage against range for the weapons, with indirect damage as a
+// second series for weapons that have it. It returns nil when none of the weapons deal
+// direct damage, along with a legend naming the color of each series.
+func renderDamageChart(weapons []*PlanetsideWeapon) (*bytes.Buffer, []string, error) {
+	series := make([]chartSeries, 0, len(weapons)*2)
+	legend := make([]string, 0, len(weapons)*2)
+	xMax, yMax := 0.0, 0.0
+
+	for i, weapon := range weapons {
+		if weapon.MaxDamage <= 0 {
+			continue
+		}
+
+		w := weapon
+		direct := chartPalette[(i*2)%len(chartPalette)]
+		series = append(series, chartSeries{
+			color: direct.color,
+			value: func(x float64) (float64, bool) { return damageAtRange(w, x), true },
+		})
+		legend = append(legend, fmt.Sprintf("%s: %s", direct.name, weaponSeriesName(w, "damage", len(weapons))))
+
+		// Some weapons gain damage over range, so the curve peaks at whichever end is higher.
+		xMax = math.Max(xMax, float64(w.MinDamageRange))
+		yMax = math.Max(yMax, math.Max(float64(w.MaxDamage), float64(w.MinDamage)))
+
+		if w.IndirectMaxDamage > 0 {
+			indirect := chartPalette[(i*2+1)%len(chartPalette)]
+			series = append(series, chartSeries{
+				color: indirect.color,
+				value: func(x float64) (float64, bool) { return indirectDamageAtRange(w, x) },
+			})
+			legend = append(legend, fmt.Sprintf("%s: %s", indirect.name, weaponSeriesName(w, "indirect damage", len(weapons))))
+
+			xMax = math.Max(xMax, float64(w.IndirectMinDamageRange))
+			yMax = math.Max(yMax, math.Max(float64(w.IndirectMaxDamage), float64(w.IndirectMinDamage)))
+		}
+	}
+
+	if len(series) == 0 {
+		return nil, nil, nil
+	}
+
+	// Leave room past the last falloff point so the flat tail of the curve is visible.
+	xLimit, yLimit := math.Max(xMax*1.2, 50), yMax*1.1
+	xStep, yStep := chartTickStep(xLimit), chartTickStep(yLimit)
+
+	chart, err := renderLineChart(series, math.Ceil(xLimit/xStep)*xStep, math.Ceil(yLimit/yStep)*yStep, xStep, yStep, "m")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return chart, legend, nil
+}
+
+func weaponSeriesName(weapon *PlanetsideWeapon, series string, weaponCount int) string {
+	if weaponCount == 1 {
+		return strings.Title(series)
+	}
+
+	return weapon.Name + " " + series
+}
+
+// indirectDamageAtRange falls off linearly across the splash radius, with no damage past it.
+func indirectDamageAtRange(weapon *PlanetsideWeapon, distance float64) (float64, bool) {
+	maxRange, minRange := float64(weapon.IndirectMaxDamageRange), float64(weapon.IndirectMinDamageRange)
+
+	if distance > minRange {
+		return 0, false
+	}
+
+	if distance <= maxRange || minRange <= maxRange {
+		return float64(weapon.IndirectMaxDamage), true
+	}
+
+	progress := (distance - maxRange) / (minRange - maxRange)
+
+	return float64(weapon.IndirectMaxDamage) + progress*float64(weapon.IndirectMinDamage-weapon.IndirectMaxDamage), true
+}
+
+// chartTickStep picks the smallest step that labels the axis with at most chartMaxTicks ticks.
+func chartTickStep(max float64) float64 {
+	for _, step := range chartTickSteps {
+		if max/step <= chartMaxTicks {
+			return step
+		}
+	}
+
+	return chartTickSteps[len(chartTickSteps)-1]
+}
+
+// renderLineChart draws the series over a grid from zero to xMax and yMax and encodes it as a PNG.
+func renderLineChart(series []chartSeries, xMax float64, yMax float64, xStep float64, yStep float64, xUnit string) (*bytes.Buffer, error) {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	left, right := chartMarginLeft, chartWidth-chartMarginRight
+	top, bottom := chartMarginTop, chartHeight-chartMarginBottom
+
+	toX := func(x float64) int { return left + int(math.Round(x/xMax*float64(right-left))) }
+	toY := func(y float64) int { return bottom - int(math.Round(y/yMax*float64(bottom-top))) }
+
+	glyphHeight := 5 * chartFontScale
+
+	for y := 0.0; y <= yMax; y += yStep {
+		py := toY(y)
+		drawChartLine(img, left, py, right, py, chartGrid, 1)
+
+		label := fmt.Sprintf("%0.f", y)
+		drawChartText(img, label, left-8-chartTextWidth(label), py-glyphHeight/2, chartForeground)
+	}
+
+	for x := 0.0; x <= xMax; x += xStep {
+		px := toX(x)
+		drawChartLine(img, px, top, px, bottom, chartGrid, 1)
+
+		label := fmt.Sprintf("%0.f%s", x, xUnit)
+		drawChartText(img, label, px-chartTextWidth(label)/2, bottom+8, chartForeground)
+	}
+
+	drawChartLine(img, left, top, left, bottom, chartForeground, 1)
+	drawChartLine(img, left, bottom, right, bottom, chartForeground, 1)
+
+	for _, s := range series {
+		previousX, previousY, previousOK := 0, 0, false
+
+		for px := left; px <= right; px++ {
+			value, ok := s.value(float64(px-left) / float64(right-left) * xMax)
+			py := toY(value)
+
+			if ok && previousOK {
+				drawChartLine(img, previousX, previousY, px, py, s.color, chartLineWidth)
+			}
+
+			previousX, previousY, previousOK = px, py, ok
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	err := png.Encode(buffer, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer, nil
+}
+
+// drawChartLine draws a line of the given width using Bresenham's algorithm.
+func drawChartLine(img *image.RGBA, x0 int, y0 int, x1 int, y1 int, c color.RGBA, width int) {
+	dx, dy := absInt(x1-x0), -absInt(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		for ox := 0; ox < width; ox++ {
+			for oy := 0; oy < width; oy++ {
+				img.SetRGBA(x0+ox-width/2, y0+oy-width/2, c)
+			}
+		}
+
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func drawChartText(img *image.RGBA, text string, x int, y int, c color.RGBA) {
+	for _, r := range text {
+		glyph, ok := chartGlyphs[r]
+		if ok {
+			for row, bits := range glyph {
+				for column, bit := range bits {
+					if bit != '1' {
+						continue
+					}
+
+					for sx := 0; sx < chartFontScale; sx++ {
+						for sy := 0; sy < chartFontScale; sy++ {
+							img.SetRGBA(x+column*chartFontScale+sx, y+row*chartFontScale+sy, c)
+						}
+					}
+				}
+			}
+		}
+
+		x += 4 * chartFontScale
+	}
+}
+
+func chartTextWidth(text string) int {
+	count := len([]rune(text))
+	if count == 0 {
+		return 0
+	}
+
+	return count*4*chartFontScale - chartFontScale
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+// sendEmbedWithChart sends the embed with the chart attached as its image, falling back to the
+// plain embed when there is no chart.
+func (p *planetsidetwoPlugin) sendEmbedWithChart(client *discordgobot.DiscordClient, channelID string, embed *discordgo.MessageEmbed, chart *bytes.Buffer) {
+	p.RLock()
+	defer p.RUnlock()
+
+	if chart == nil || len(client.Sessions) == 0 {
+		client.SendEmbedMessage(channelID, embed)
+		return
+	}
+
+	embed.Image = &discordgo.MessageEmbedImage{
+		URL: "attachment://" + damageChartFileName,
+	}
+
+	_, err := client.Sessions[0].ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embed: embed,
+		Files: []*discordgo.File{
+			&discordgo.File{
+				Name:        damageChartFileName,
+				ContentType: "image/png",
+				Reader:      chart,
+			},
+		},
+	})
+
+	if err != nil {
+		log.Printf("Failed to send embed with chart: %s", err)
+	}
+}
@@ -0,0 +1,170 @@
+package planetsidetwoplugin
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestIndirectDamageAtRange(t *testing.T) {
+	falloff := &PlanetsideWeapon{IndirectMaxDamage: 1000, IndirectMaxDamageRange: 1, IndirectMinDamage: 100, IndirectMinDamageRange: 4}
+	flat := &PlanetsideWeapon{IndirectMaxDamage: 500, IndirectMaxDamageRange: 3, IndirectMinDamage: 500, IndirectMinDamageRange: 3}
+
+	tests := []struct {
+		weapon   *PlanetsideWeapon
+		distance float64
+		expected float64
+		ok       bool
+	}{
+		{falloff, 0, 1000, true},
+		{falloff, 1, 1000, true},
+		{falloff, 2.5, 550, true},
+		{falloff, 4, 100, true},
+		{falloff, 4.5, 0, false},
+		{flat, 2, 500, true},
+		{flat, 3, 500, true},
+		{flat, 3.1, 0, false},
+	}
+
+	for _, test := range tests {
+		actual, ok := indirectDamageAtRange(test.weapon, test.distance)
+		if actual != test.expected || ok != test.ok {
+			t.Errorf("indirectDamageAtRange(%+v, %v) = %v, %t, want %v, %t", test.weapon, test.distance, actual, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestChartTickStep(t *testing.T) {
+	tests := []struct {
+		max      float64
+		expected float64
+	}{
+		{0, 5},
+		{50, 5},
+		{51, 10},
+		{100, 10},
+		{101, 25},
+		{660, 100},
+		{1100, 250},
+		{1000000, 5000},
+	}
+
+	for _, test := range tests {
+		if actual := chartTickStep(test.max); actual != test.expected {
+			t.Errorf("chartTickStep(%v) = %v, want %v", test.max, actual, test.expected)
+		}
+	}
+}
+
+func TestRenderDamageChart(t *testing.T) {
+	carbine := &PlanetsideWeapon{Name: "T5 AMC", MaxDamage: 143, MaxDamageRange: 10, MinDamage: 112, MinDamageRange: 65}
+	launcher := &PlanetsideWeapon{
+		Name:                   "ML-7",
+		MaxDamage:              750,
+		MaxDamageRange:         10,
+		MinDamage:              750,
+		MinDamageRange:         10,
+		IndirectMaxDamage:      300,
+		IndirectMaxDamageRange: 1,
+		IndirectMinDamage:      50,
+		IndirectMinDamageRange: 4,
+	}
+	rising := &PlanetsideWeapon{Name: "Lancer", IsVehicleWeapon: true, MaxDamage: 100, MaxDamageRange: 0, MinDamage: 300, MinDamageRange: 50}
+	indirectOnly := &PlanetsideWeapon{Name: "Frag Grenade", IndirectMaxDamage: 1000, IndirectMaxDamageRange: 1, IndirectMinDamage: 100, IndirectMinDamageRange: 4}
+
+	tests := []struct {
+		name     string
+		weapons  []*PlanetsideWeapon
+		expected []string
+	}{
+		{"single weapon", []*PlanetsideWeapon{carbine}, []string{"orange: Damage"}},
+		{"indirect damage", []*PlanetsideWeapon{launcher}, []string{"orange: Damage", "blue: Indirect Damage"}},
+		{"vehicle weapon", []*PlanetsideWeapon{rising}, []string{"orange: Damage"}},
+		{
+			"overlay",
+			[]*PlanetsideWeapon{carbine, launcher},
+			[]string{"orange: T5 AMC damage", "green: ML-7 damage", "pink: ML-7 indirect damage"},
+		},
+		{"overlay skips weapons without direct damage", []*PlanetsideWeapon{indirectOnly, carbine}, []string{"green: T5 AMC damage"}},
+		{"no direct damage", []*PlanetsideWeapon{indirectOnly}, nil},
+		{"no weapons", nil, nil},
+	}
+
+	for _, test := range tests {
+		chart, legend, err := renderDamageChart(test.weapons)
+		if err != nil {
+			t.Errorf("%s: renderDamageChart returned error %v", test.name, err)
+			continue
+		}
+
+		if test.expected == nil {
+			if chart != nil || legend != nil {
+				t.Errorf("%s: renderDamageChart = %t, %v, want no chart", test.name, chart != nil, legend)
+			}
+			continue
+		}
+
+		if chart == nil {
+			t.Errorf("%s: renderDamageChart returned no chart", test.name)
+			continue
+		}
+
+		img, err := png.Decode(chart)
+		if err != nil {
+			t.Errorf("%s: renderDamageChart returned an invalid PNG: %v", test.name, err)
+			continue
+		}
+
+		if size := img.Bounds().Size(); size.X != chartWidth || size.Y != chartHeight {
+			t.Errorf("%s: renderDamageChart drew %dx%d, want %dx%d", test.name, size.X, size.Y, chartWidth, chartHeight)
+		}
+
+		if len(legend) != len(test.expected) {
+			t.Errorf("%s: renderDamageChart legend = %v, want %v", test.name, legend, test.expected)
+			continue
+		}
+
+		for i, expected := range test.expected {
+			if legend[i] != expected {
+				t.Errorf("%s: renderDamageChart legend %d = %q, want %q", test.name, i, legend[i], expected)
+			}
+		}
+	}
+}
+
+func TestRenderDamageChartFitsRisingDamage(t *testing.T) {
+	weapon := &PlanetsideWeapon{Name: "Lancer", MaxDamage: 100, MaxDamageRange: 0, MinDamage: 300, MinDamageRange: 50}
+
+	chart, _, err := renderDamageChart([]*PlanetsideWeapon{weapon})
+	if err != nil || chart == nil {
+		t.Fatalf("renderDamageChart = %v, %v, want a chart", chart, err)
+	}
+
+	img, err := png.Decode(chart)
+	if err != nil {
+		t.Fatalf("renderDamageChart returned an invalid PNG: %v", err)
+	}
+
+	// Past the falloff the curve sits at its peak, which must be drawn inside the plot.
+	right := chartWidth - chartMarginRight
+	if !chartHasColor(img, right-10, right, chartPalette[0].color) {
+		t.Error("renderDamageChart clipped the top of a curve that rises with range")
+	}
+}
+
+// chartHasColor reports whether any pixel in the columns from x0 to x1 has the color.
+func chartHasColor(img image.Image, x0 int, x1 int, c color.Color) bool {
+	wantR, wantG, wantB, _ := c.RGBA()
+
+	for x := x0; x <= x1; x++ {
+		for y := 0; y < chartHeight; y++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if r == wantR && g == wantG && b == wantB {
+				return true
+			}
+		}
+	}
+
+	return false
+}
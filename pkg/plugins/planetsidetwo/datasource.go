@@ -0,0 +1,195 @@
+package planetsidetwoplugin
+
+import (
+	"log"
+	"strings"
+)
+
+const (
+	dataSourceVoidwell = "voidwell"
+	dataSourceCensus   = "census"
+	dataSourceNone     = "none"
+)
+
+// planetsideDataSource is a backend able to answer the lookups made by the PS2 commands.
+type planetsideDataSource interface {
+	Name() string
+	GetCharacter(characterName string, platform string) (*PlanetsideCharacter, error)
+	GetCharacterByID(characterID string, platform string) (*PlanetsideCharacter, error)
+	GetCharacterWeapon(characterName string, weaponName string, platform string) (*PlanetsideCharacterWeapon, error)
+	GetOutfit(outfitAlias string, platform string) (*PlanetsideOutfit, error)
+	GetOutfitMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error)
+	GetOutfitOnlineMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error)
+	GetWorldStates(platform string) ([]*PlanetsideWorldState, error)
+	GetWeapon(weaponName string) (*PlanetsideWeapon, error)
+	SearchWeapons(query string) ([]*PlanetsideWeaponSearchResult, error)
+	ListWeapons() ([]*PlanetsideWeaponSearchResult, error)
+}
+
+// newDataSource builds the data source chosen by the operator. An empty primary
+// defaults to Voidwell and an empty fallback defaults to Census.
+func newDataSource(primaryName string, fallbackName string, voidwell *voidwellClient) planetsideDataSource {
+	if primaryName == "" {
+		primaryName = dataSourceVoidwell
+	}
+
+	if fallbackName == "" {
+		fallbackName = dataSourceCensus
+	}
+
+	primary := createDataSource(primaryName, voidwell)
+	if primary == nil {
+		log.Printf("Unknown PlanetSide data source '%s', using %s", primaryName, dataSourceVoidwell)
+		primary = createDataSource(dataSourceVoidwell, voidwell)
+	}
+
+	fallback := createDataSource(fallbackName, voidwell)
+	if fallback == nil || fallback.Name() == primary.Name() {
+		return primary
+	}
+
+	return &fallbackDataSource{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func createDataSource(name string, voidwell *voidwellClient) planetsideDataSource {
+	switch strings.ToLower(name) {
+	case dataSourceVoidwell:
+		return newVoidwellDataSource(voidwell)
+	case dataSourceCensus:
+		return newCensusDataSource()
+	}
+
+	return nil
+}
+
+// unavailableError is implemented by data source errors that can tell a service outage apart
+// from a lookup that failed on its own, such as a character that does not exist.
+type unavailableError interface {
+	unavailable() bool
+}
+
+// isUnavailableError reports whether err means the data source could not answer, including
+// lookups it does not support, rather than that the answer was a miss or a bad request.
+func isUnavailableError(err error) bool {
+	typed, ok := err.(unavailableError)
+	return ok && typed.unavailable()
+}
+
+// fallbackDataSource queries the primary data source and retries lookups it could not answer,
+// such as on a timeout, a rate limit or a lookup it does not support, against the fallback.
+// Misses and rejected requests are returned as they are, so the primary's error reaches the user.
+type fallbackDataSource struct {
+	primary  planetsideDataSource
+	fallback planetsideDataSource
+}
+
+func (s *fallbackDataSource) Name() string {
+	return s.primary.Name() + "+" + s.fallback.Name()
+}
+
+func (s *fallbackDataSource) GetCharacter(characterName string, platform string) (*PlanetsideCharacter, error) {
+	character, err := s.primary.GetCharacter(characterName, platform)
+	if isUnavailableError(err) {
+		s.logFallback("character", characterName, err)
+		return s.fallback.GetCharacter(characterName, platform)
+	}
+
+	return character, err
+}
+
+func (s *fallbackDataSource) GetCharacterByID(characterID string, platform string) (*PlanetsideCharacter, error) {
+	character, err := s.primary.GetCharacterByID(characterID, platform)
+	if isUnavailableError(err) {
+		s.logFallback("character", characterID, err)
+		return s.fallback.GetCharacterByID(characterID, platform)
+	}
+
+	return character, err
+}
+
+func (s *fallbackDataSource) GetCharacterWeapon(characterName string, weaponName string, platform string) (*PlanetsideCharacterWeapon, error) {
+	weapon, err := s.primary.GetCharacterWeapon(characterName, weaponName, platform)
+	if isUnavailableError(err) {
+		s.logFallback("character weapon", characterName+"/"+weaponName, err)
+		return s.fallback.GetCharacterWeapon(characterName, weaponName, platform)
+	}
+
+	return weapon, err
+}
+
+func (s *fallbackDataSource) GetOutfit(outfitAlias string, platform string) (*PlanetsideOutfit, error) {
+	outfit, err := s.primary.GetOutfit(outfitAlias, platform)
+	if isUnavailableError(err) {
+		s.logFallback("outfit", outfitAlias, err)
+		return s.fallback.GetOutfit(outfitAlias, platform)
+	}
+
+	return outfit, err
+}
+
+func (s *fallbackDataSource) GetOutfitMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	members, err := s.primary.GetOutfitMembers(outfitID, platform)
+	if isUnavailableError(err) {
+		s.logFallback("outfit members", outfitID, err)
+		return s.fallback.GetOutfitMembers(outfitID, platform)
+	}
+
+	return members, err
+}
+
+func (s *fallbackDataSource) GetOutfitOnlineMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	members, err := s.primary.GetOutfitOnlineMembers(outfitID, platform)
+	if isUnavailableError(err) {
+		s.logFallback("outfit online members", outfitID, err)
+		return s.fallback.GetOutfitOnlineMembers(outfitID, platform)
+	}
+
+	return members, err
+}
+
+func (s *fallbackDataSource) GetWorldStates(platform string) ([]*PlanetsideWorldState, error) {
+	worlds, err := s.primary.GetWorldStates(platform)
+	if isUnavailableError(err) {
+		s.logFallback("world state", platform, err)
+		return s.fallback.GetWorldStates(platform)
+	}
+
+	return worlds, err
+}
+
+func (s *fallbackDataSource) GetWeapon(weaponName string) (*PlanetsideWeapon, error) {
+	weapon, err := s.primary.GetWeapon(weaponName)
+	if isUnavailableError(err) {
+		s.logFallback("weapon", weaponName, err)
+		return s.fallback.GetWeapon(weaponName)
+	}
+
+	return weapon, err
+}
+
+func (s *fallbackDataSource) SearchWeapons(query string) ([]*PlanetsideWeaponSearchResult, error) {
+	results, err := s.primary.SearchWeapons(query)
+	if isUnavailableError(err) {
+		s.logFallback("weapon search", query, err)
+		return s.fallback.SearchWeapons(query)
+	}
+
+	return results, err
+}
+
+func (s *fallbackDataSource) ListWeapons() ([]*PlanetsideWeaponSearchResult, error) {
+	results, err := s.primary.ListWeapons()
+	if isUnavailableError(err) {
+		s.logFallback("weapon list", "all", err)
+		return s.fallback.ListWeapons()
+	}
+
+	return results, err
+}
+
+func (s *fallbackDataSource) logFallback(lookup string, query string, err error) {
+	log.Printf("%s %s lookup for '%s' failed, falling back to %s: %s", s.primary.Name(), lookup, query, s.fallback.Name(), err)
+}
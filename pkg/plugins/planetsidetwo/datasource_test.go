@@ -0,0 +1,110 @@
+package planetsidetwoplugin
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeDataSource answers character, online member and weapon list lookups with a fixed result,
+// counting the calls it gets.
+type fakeDataSource struct {
+	planetsideDataSource
+	name      string
+	character *PlanetsideCharacter
+	members   []*PlanetsideOutfitMember
+	weapons   []*PlanetsideWeaponSearchResult
+	err       error
+	calls     int
+}
+
+func (s *fakeDataSource) Name() string {
+	return s.name
+}
+
+func (s *fakeDataSource) GetCharacter(characterName string, platform string) (*PlanetsideCharacter, error) {
+	s.calls++
+	return s.character, s.err
+}
+
+func (s *fakeDataSource) GetOutfitOnlineMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	s.calls++
+	return s.members, s.err
+}
+
+func (s *fakeDataSource) ListWeapons() ([]*PlanetsideWeaponSearchResult, error) {
+	s.calls++
+	return s.weapons, s.err
+}
+
+func TestFallbackDataSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		primaryErr   error
+		fallsBack    bool
+		expectedName string
+	}{
+		{"success", nil, false, "primary"},
+		{"not found", &voidwellError{Kind: voidwellNotFound}, false, ""},
+		{"bad request", &voidwellError{Kind: voidwellRejected, StatusCode: 400}, false, ""},
+		{"timeout", &voidwellError{Kind: voidwellTimeout}, true, "fallback"},
+		{"rate limited", &voidwellError{Kind: voidwellRateLimited, StatusCode: 429}, true, "fallback"},
+		{"server error", &voidwellError{Kind: voidwellUnavailable, StatusCode: 503}, true, "fallback"},
+		{"census unavailable", &censusError{Kind: censusUnavailable}, true, "fallback"},
+		{"census unsupported", &censusError{Kind: censusUnsupported, Subject: "character weapon stats"}, true, "fallback"},
+		{"voidwell unsupported", &voidwellError{Kind: voidwellUnsupported, Subject: "online outfit members"}, true, "fallback"},
+		{"census not found", &censusError{Kind: censusNotFound}, false, ""},
+		{"untyped", errors.New("Unknown platform"), false, ""},
+	}
+
+	for _, test := range tests {
+		primary := &fakeDataSource{name: "primary", err: test.primaryErr}
+		if test.primaryErr == nil {
+			primary.character = &PlanetsideCharacter{Name: "primary"}
+		}
+		fallback := &fakeDataSource{name: "fallback", character: &PlanetsideCharacter{Name: "fallback"}}
+
+		source := &fallbackDataSource{primary: primary, fallback: fallback}
+		character, err := source.GetCharacter("Foo", platformPC)
+
+		if (fallback.calls > 0) != test.fallsBack {
+			t.Errorf("%s: fell back %t, want %t", test.name, fallback.calls > 0, test.fallsBack)
+		}
+
+		if test.expectedName == "" {
+			if err != test.primaryErr {
+				t.Errorf("%s: returned error %v, want the primary's %v", test.name, err, test.primaryErr)
+			}
+			continue
+		}
+
+		if err != nil || character == nil || character.Name != test.expectedName {
+			t.Errorf("%s: returned %+v, %v, want the %s character", test.name, character, err, test.expectedName)
+		}
+	}
+}
+
+func TestFallbackDataSourceOnlineMembers(t *testing.T) {
+	fallback := &fakeDataSource{name: dataSourceCensus, members: []*PlanetsideOutfitMember{{Name: "Foo"}}}
+	source := &fallbackDataSource{primary: newVoidwellDataSource(nil), fallback: fallback}
+
+	members, err := source.GetOutfitOnlineMembers("37509488620604883", platformPC)
+
+	if fallback.calls != 1 {
+		t.Errorf("made %d fallback calls, want 1", fallback.calls)
+	}
+
+	if err != nil || len(members) != 1 || members[0].Name != "Foo" {
+		t.Errorf("GetOutfitOnlineMembers = %+v, %v, want the fallback's members", members, err)
+	}
+}
+
+func TestFallbackDataSourceListWeapons(t *testing.T) {
+	fallback := &fakeDataSource{name: dataSourceCensus, weapons: []*PlanetsideWeaponSearchResult{{Name: "NS-11A"}}}
+	source := &fallbackDataSource{primary: newVoidwellDataSource(nil), fallback: fallback}
+
+	weapons, err := source.ListWeapons()
+
+	if err != nil || len(weapons) != 1 || fallback.calls != 1 {
+		t.Errorf("ListWeapons = %+v, %v with %d fallback calls, want the fallback's weapons", weapons, err, fallback.calls)
+	}
+}
@@ -0,0 +1,616 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	CENSUS_EVENTSTREAM_URI = "wss://push.planetside2.com/streaming"
+
+	// The stream sends a heartbeat every 30 seconds, so a silent connection is treated as dead well after that.
+	eventStreamReadTimeout    = 90 * time.Second
+	eventStreamMinBackoff     = time.Second
+	eventStreamMaxBackoff     = 2 * time.Minute
+	eventSubscriberBufferSize = 64
+)
+
+const (
+	eventPlayerLogin           = "PlayerLogin"
+	eventPlayerLogout          = "PlayerLogout"
+	eventDeath                 = "Death"
+	eventPlayerFacilityCapture = "PlayerFacilityCapture"
+	eventPlayerFacilityDefend  = "PlayerFacilityDefend"
+	eventFacilityControl       = "FacilityControl"
+	eventMetagameEvent         = "MetagameEvent"
+)
+
+// censusEnvironments maps platforms to their event stream environment.
+var censusEnvironments = map[string]string{
+	platformPC:    "ps2",
+	platformPS4US: "ps2ps4us",
+	platformPS4EU: "ps2ps4eu",
+}
+
+// planetsideEvent is a typed event received from the Census event stream.
+type planetsideEvent interface {
+	EventName() string
+	WorldID() string
+	CharacterIDs() []string
+}
+
+type censusEventBase struct {
+	Name      string `json:"event_name"`
+	Timestamp string `json:"timestamp"`
+	World     string `json:"world_id"`
+}
+
+func (e *censusEventBase) EventName() string {
+	return e.Name
+}
+
+func (e *censusEventBase) WorldID() string {
+	return e.World
+}
+
+func (e *censusEventBase) CharacterIDs() []string {
+	return nil
+}
+
+func (e *censusEventBase) Time() time.Time {
+	return time.Unix(int64(censusAtoi(e.Timestamp)), 0).UTC()
+}
+
+type PlayerLoginEvent struct {
+	censusEventBase
+	CharacterID string `json:"character_id"`
+}
+
+func (e *PlayerLoginEvent) CharacterIDs() []string {
+	return []string{e.CharacterID}
+}
+
+type PlayerLogoutEvent struct {
+	censusEventBase
+	CharacterID string `json:"character_id"`
+}
+
+func (e *PlayerLogoutEvent) CharacterIDs() []string {
+	return []string{e.CharacterID}
+}
+
+type DeathEvent struct {
+	censusEventBase
+	CharacterID         string `json:"character_id"`
+	CharacterLoadoutID  string `json:"character_loadout_id"`
+	AttackerCharacterID string `json:"attacker_character_id"`
+	AttackerLoadoutID   string `json:"attacker_loadout_id"`
+	AttackerWeaponID    string `json:"attacker_weapon_id"`
+	AttackerVehicleID   string `json:"attacker_vehicle_id"`
+	IsHeadshot          string `json:"is_headshot"`
+	ZoneID              string `json:"zone_id"`
+}
+
+func (e *DeathEvent) CharacterIDs() []string {
+	return []string{e.CharacterID, e.AttackerCharacterID}
+}
+
+func (e *DeathEvent) Headshot() bool {
+	return e.IsHeadshot == "1"
+}
+
+type PlayerFacilityCaptureEvent struct {
+	censusEventBase
+	CharacterID string `json:"character_id"`
+	FacilityID  string `json:"facility_id"`
+	OutfitID    string `json:"outfit_id"`
+	ZoneID      string `json:"zone_id"`
+}
+
+func (e *PlayerFacilityCaptureEvent) CharacterIDs() []string {
+	return []string{e.CharacterID}
+}
+
+type PlayerFacilityDefendEvent struct {
+	censusEventBase
+	CharacterID string `json:"character_id"`
+	FacilityID  string `json:"facility_id"`
+	OutfitID    string `json:"outfit_id"`
+	ZoneID      string `json:"zone_id"`
+}
+
+func (e *PlayerFacilityDefendEvent) CharacterIDs() []string {
+	return []string{e.CharacterID}
+}
+
+type FacilityControlEvent struct {
+	censusEventBase
+	FacilityID   string `json:"facility_id"`
+	OutfitID     string `json:"outfit_id"`
+	NewFactionID string `json:"new_faction_id"`
+	OldFactionID string `json:"old_faction_id"`
+	DurationHeld string `json:"duration_held"`
+	ZoneID       string `json:"zone_id"`
+}
+
+type MetagameEvent struct {
+	censusEventBase
+	InstanceID         string `json:"instance_id"`
+	MetagameEventID    string `json:"metagame_event_id"`
+	MetagameEventState string `json:"metagame_event_state_name"`
+	FactionVS          string `json:"faction_vs"`
+	FactionNC          string `json:"faction_nc"`
+	FactionTR          string `json:"faction_tr"`
+	ExperienceBonus    string `json:"experience_bonus"`
+	ZoneID             string `json:"zone_id"`
+}
+
+// UnknownEvent carries event types that do not have a typed representation yet.
+type UnknownEvent struct {
+	censusEventBase
+	Payload json.RawMessage `json:"-"`
+}
+
+// eventFilter selects the events a subscriber receives. Empty lists match everything, so
+// a subscriber interested in every login on a world only sets Worlds and EventNames.
+type eventFilter struct {
+	Characters []string
+	Worlds     []string
+	EventNames []string
+}
+
+func (f eventFilter) matches(event planetsideEvent) bool {
+	if len(f.EventNames) > 0 && !containsString(f.EventNames, event.EventName()) {
+		return false
+	}
+
+	if len(f.Worlds) > 0 && !containsString(f.Worlds, event.WorldID()) {
+		return false
+	}
+
+	if len(f.Characters) > 0 {
+		for _, characterID := range event.CharacterIDs() {
+			if containsString(f.Characters, characterID) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// subscriptionKeys translates the filter into what Census is asked to send. Census only sends
+// events for the listed characters or worlds, so a filter without either asks for all worlds.
+func (f eventFilter) subscriptionKeys() ([]string, []string, []string) {
+	worlds, eventNames := f.Worlds, f.EventNames
+
+	if len(f.Characters) == 0 && len(worlds) == 0 {
+		worlds = []string{"all"}
+	}
+
+	if len(eventNames) == 0 {
+		eventNames = []string{"all"}
+	}
+
+	return f.Characters, worlds, eventNames
+}
+
+type eventSubscriber struct {
+	filter  eventFilter
+	events  chan planetsideEvent
+	handler func(event planetsideEvent)
+}
+
+type eventStreamMessage struct {
+	Service string          `json:"service"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type eventStreamCommand struct {
+	Service    string   `json:"service"`
+	Action     string   `json:"action"`
+	Characters []string `json:"characters,omitempty"`
+	Worlds     []string `json:"worlds,omitempty"`
+	EventNames []string `json:"eventNames,omitempty"`
+}
+
+func (c eventStreamCommand) empty() bool {
+	return len(c.Characters) == 0 && len(c.Worlds) == 0 && len(c.EventNames) == 0
+}
+
+// eventStream is a long lived connection to the Census event stream for one platform. It
+// connects on the first subscription, reconnects with backoff when the connection drops and
+// fans events out to every subscriber whose filter matches.
+type eventStream struct {
+	sync.Mutex
+	uri         string
+	conn        *websocket.Conn
+	running     bool
+	subscribers map[int]*eventSubscriber
+	nextID      int
+	characters  map[string]int
+	worlds      map[string]int
+	eventNames  map[string]int
+	received    int
+	dropped     int
+	reconnects  int
+}
+
+func newEventStream(uri string) *eventStream {
+	return &eventStream{
+		uri:         uri,
+		subscribers: make(map[int]*eventSubscriber),
+		characters:  make(map[string]int),
+		worlds:      make(map[string]int),
+		eventNames:  make(map[string]int),
+	}
+}
+
+// eventStreamURI builds the stream address for a platform. PS2EventStreamURI overrides the
+// endpoint so that a local websocket server can stand in for Census.
+func eventStreamURI(platform string, serviceID string) string {
+	base := os.Getenv("PS2EventStreamURI")
+	if base == "" {
+		base = CENSUS_EVENTSTREAM_URI
+	}
+
+	environment, ok := censusEnvironments[platform]
+	if !ok {
+		environment = censusEnvironments[platformPC]
+	}
+
+	query := url.Values{}
+	query.Set("environment", environment)
+	query.Set("service-id", "s:"+serviceID)
+
+	return base + "?" + query.Encode()
+}
+
+// subscribe registers handler for events matching filter and returns a function that removes
+// the subscription. Handlers for one subscriber are called sequentially from their own goroutine.
+func (s *eventStream) subscribe(filter eventFilter, handler func(event planetsideEvent)) func() {
+	subscriber := &eventSubscriber{
+		filter:  filter,
+		events:  make(chan planetsideEvent, eventSubscriberBufferSize),
+		handler: handler,
+	}
+
+	go func() {
+		for event := range subscriber.events {
+			subscriber.handler(event)
+		}
+	}()
+
+	characters, worlds, eventNames := filter.subscriptionKeys()
+
+	s.Lock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = subscriber
+
+	added := eventStreamCommand{
+		Service:    "event",
+		Action:     "subscribe",
+		Characters: retainKeys(s.characters, characters),
+		Worlds:     retainKeys(s.worlds, worlds),
+		EventNames: retainKeys(s.eventNames, eventNames),
+	}
+
+	if !s.running {
+		s.running = true
+		go s.run()
+	} else if s.conn != nil && !added.empty() {
+		s.send(added)
+	}
+	s.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.unsubscribe(id)
+		})
+	}
+}
+
+func (s *eventStream) unsubscribe(id int) {
+	s.Lock()
+	defer s.Unlock()
+
+	subscriber, ok := s.subscribers[id]
+	if !ok {
+		return
+	}
+
+	delete(s.subscribers, id)
+	close(subscriber.events)
+
+	characters, worlds, eventNames := subscriber.filter.subscriptionKeys()
+
+	removed := eventStreamCommand{
+		Service:    "event",
+		Action:     "clearSubscribe",
+		Characters: releaseKeys(s.characters, characters),
+		Worlds:     releaseKeys(s.worlds, worlds),
+		EventNames: releaseKeys(s.eventNames, eventNames),
+	}
+
+	if s.conn != nil && !removed.empty() {
+		s.send(removed)
+	}
+}
+
+// run keeps the stream connected until every subscriber is gone.
+func (s *eventStream) run() {
+	backoff := eventStreamMinBackoff
+
+	for {
+		s.Lock()
+		if len(s.subscribers) == 0 {
+			s.running = false
+			s.Unlock()
+			return
+		}
+		s.Unlock()
+
+		connectedAt := time.Now()
+		err := s.connect()
+		if err != nil {
+			log.Printf("Event stream error: %s", err)
+		}
+
+		// A connection that stayed up for a while was healthy, so start the backoff over.
+		if time.Since(connectedAt) > eventStreamMaxBackoff {
+			backoff = eventStreamMinBackoff
+		}
+
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		time.Sleep(delay)
+
+		backoff *= 2
+		if backoff > eventStreamMaxBackoff {
+			backoff = eventStreamMaxBackoff
+		}
+
+		s.Lock()
+		s.reconnects++
+		s.Unlock()
+	}
+}
+
+func (s *eventStream) connect() error {
+	conn, _, err := websocket.DefaultDialer.Dial(s.uri, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.Lock()
+	s.conn = conn
+	err = s.send(s.fullSubscription())
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		s.conn = nil
+		s.Unlock()
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(eventStreamReadTimeout))
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var message eventStreamMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			continue
+		}
+
+		if message.Service != "event" || message.Type != "serviceMessage" || len(message.Payload) == 0 {
+			continue
+		}
+
+		event, err := parsePlanetsideEvent(message.Payload)
+		if err != nil {
+			log.Printf("Failed to parse event: %s", err)
+			continue
+		}
+
+		s.dispatch(event)
+	}
+}
+
+func (s *eventStream) dispatch(event planetsideEvent) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.received++
+
+	for _, subscriber := range s.subscribers {
+		if !subscriber.filter.matches(event) {
+			continue
+		}
+
+		select {
+		case subscriber.events <- event:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// send writes a command to the connection. The caller must hold the lock.
+func (s *eventStream) send(command eventStreamCommand) error {
+	if s.conn == nil {
+		return fmt.Errorf("Event stream is not connected")
+	}
+
+	return s.conn.WriteJSON(command)
+}
+
+// fullSubscription is the union of every subscriber's filter, sent after each connect. The caller must hold the lock.
+func (s *eventStream) fullSubscription() eventStreamCommand {
+	return eventStreamCommand{
+		Service:    "event",
+		Action:     "subscribe",
+		Characters: mapKeys(s.characters),
+		Worlds:     mapKeys(s.worlds),
+		EventNames: mapKeys(s.eventNames),
+	}
+}
+
+func (s *eventStream) stats() map[string]string {
+	s.Lock()
+	defer s.Unlock()
+
+	connected := "no"
+	if s.conn != nil {
+		connected = "yes"
+	}
+
+	return map[string]string{
+		"Connected":   connected,
+		"Subscribers": strconv.Itoa(len(s.subscribers)),
+		"Events":      strconv.Itoa(s.received),
+		"Dropped":     strconv.Itoa(s.dropped),
+		"Reconnects":  strconv.Itoa(s.reconnects),
+	}
+}
+
+func parsePlanetsideEvent(payload json.RawMessage) (planetsideEvent, error) {
+	var base censusEventBase
+	if err := json.Unmarshal(payload, &base); err != nil {
+		return nil, err
+	}
+
+	var event planetsideEvent
+	switch base.Name {
+	case eventPlayerLogin:
+		event = &PlayerLoginEvent{}
+	case eventPlayerLogout:
+		event = &PlayerLogoutEvent{}
+	case eventDeath:
+		event = &DeathEvent{}
+	case eventPlayerFacilityCapture:
+		event = &PlayerFacilityCaptureEvent{}
+	case eventPlayerFacilityDefend:
+		event = &PlayerFacilityDefendEvent{}
+	case eventFacilityControl:
+		event = &FacilityControlEvent{}
+	case eventMetagameEvent:
+		event = &MetagameEvent{}
+	default:
+		return &UnknownEvent{censusEventBase: base, Payload: payload}, nil
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// retainKeys increments the reference count of each key and returns the keys that are new.
+func retainKeys(counts map[string]int, keys []string) []string {
+	added := make([]string, 0)
+	for _, key := range keys {
+		if counts[key] == 0 {
+			added = append(added, key)
+		}
+		counts[key]++
+	}
+
+	return added
+}
+
+// releaseKeys decrements the reference count of each key and returns the keys no longer in use.
+func releaseKeys(counts map[string]int, keys []string) []string {
+	removed := make([]string, 0)
+	for _, key := range keys {
+		counts[key]--
+		if counts[key] <= 0 {
+			delete(counts, key)
+			removed = append(removed, key)
+		}
+	}
+
+	return removed
+}
+
+func mapKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventStreams holds one lazily connected stream per platform.
+type eventStreams struct {
+	sync.Mutex
+	serviceID string
+	streams   map[string]*eventStream
+}
+
+func newEventStreams(serviceID string) *eventStreams {
+	return &eventStreams{
+		serviceID: serviceID,
+		streams:   make(map[string]*eventStream),
+	}
+}
+
+func (e *eventStreams) get(platform string) *eventStream {
+	e.Lock()
+	defer e.Unlock()
+
+	stream, ok := e.streams[platform]
+	if !ok {
+		stream = newEventStream(eventStreamURI(platform, e.serviceID))
+		e.streams[platform] = stream
+	}
+
+	return stream
+}
+
+func (e *eventStreams) stats() map[string]string {
+	e.Lock()
+	defer e.Unlock()
+
+	stats := make(map[string]string)
+	for platform, stream := range e.streams {
+		for name, value := range stream.stats() {
+			stats[fmt.Sprintf("PS2 event stream %s %s", platformDisplayName(platform), strings.ToLower(name))] = value
+		}
+	}
+
+	return stats
+}
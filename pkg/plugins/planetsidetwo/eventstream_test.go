@@ -0,0 +1,228 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const eventStreamTestTimeout = 5 * time.Second
+
+// fakeEventStreamServer stands in for the Census event stream. It hands every connection and
+// every command a client sends to the test.
+type fakeEventStreamServer struct {
+	*httptest.Server
+	connections chan *websocket.Conn
+	commands    chan eventStreamCommand
+
+	sync.Mutex
+	open []*websocket.Conn
+}
+
+func newFakeEventStreamServer() *fakeEventStreamServer {
+	server := &fakeEventStreamServer{
+		connections: make(chan *websocket.Conn, 8),
+		commands:    make(chan eventStreamCommand, 32),
+	}
+
+	upgrader := websocket.Upgrader{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		server.Lock()
+		server.open = append(server.open, conn)
+		server.Unlock()
+
+		server.connections <- conn
+
+		for {
+			var command eventStreamCommand
+			if err := conn.ReadJSON(&command); err != nil {
+				return
+			}
+			server.commands <- command
+		}
+	}))
+
+	return server
+}
+
+func (s *fakeEventStreamServer) uri() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func (s *fakeEventStreamServer) close() {
+	s.Lock()
+	for _, conn := range s.open {
+		conn.Close()
+	}
+	s.Unlock()
+
+	s.Close()
+}
+
+func (s *fakeEventStreamServer) nextConnection(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-s.connections:
+		return conn
+	case <-time.After(eventStreamTestTimeout):
+		t.Fatal("timed out waiting for the event stream to connect")
+	}
+
+	return nil
+}
+
+func (s *fakeEventStreamServer) nextCommand(t *testing.T) eventStreamCommand {
+	t.Helper()
+
+	select {
+	case command := <-s.commands:
+		sort.Strings(command.Characters)
+		sort.Strings(command.Worlds)
+		sort.Strings(command.EventNames)
+		return command
+	case <-time.After(eventStreamTestTimeout):
+		t.Fatal("timed out waiting for an event stream command")
+	}
+
+	return eventStreamCommand{}
+}
+
+func (s *fakeEventStreamServer) expectNoCommand(t *testing.T) {
+	t.Helper()
+
+	select {
+	case command := <-s.commands:
+		t.Fatalf("unexpected event stream command %+v", command)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func sendTestEvent(t *testing.T, conn *websocket.Conn, payload string) {
+	t.Helper()
+
+	err := conn.WriteJSON(eventStreamMessage{
+		Service: "event",
+		Type:    "serviceMessage",
+		Payload: json.RawMessage(payload),
+	})
+	if err != nil {
+		t.Fatalf("failed to send event: %s", err)
+	}
+}
+
+func expectCommand(t *testing.T, command eventStreamCommand, action string, characters []string, worlds []string, eventNames []string) {
+	t.Helper()
+
+	if command.Action != action ||
+		strings.Join(command.Characters, ",") != strings.Join(characters, ",") ||
+		strings.Join(command.Worlds, ",") != strings.Join(worlds, ",") ||
+		strings.Join(command.EventNames, ",") != strings.Join(eventNames, ",") {
+		t.Fatalf("got command %+v, want %s characters=%v worlds=%v eventNames=%v", command, action, characters, worlds, eventNames)
+	}
+}
+
+func TestEventStreamDispatchesMatchingEvents(t *testing.T) {
+	server := newFakeEventStreamServer()
+	defer server.close()
+
+	stream := newEventStream(server.uri())
+
+	received := make(chan planetsideEvent, 4)
+	unsubscribe := stream.subscribe(eventFilter{Worlds: []string{"1"}, EventNames: []string{eventPlayerLogin}}, func(event planetsideEvent) {
+		received <- event
+	})
+	defer unsubscribe()
+
+	conn := server.nextConnection(t)
+	expectCommand(t, server.nextCommand(t), "subscribe", nil, []string{"1"}, []string{eventPlayerLogin})
+
+	sendTestEvent(t, conn, `{"event_name":"PlayerLogin","world_id":"17","character_id":"2"}`)
+	sendTestEvent(t, conn, `{"event_name":"PlayerLogout","world_id":"1","character_id":"3"}`)
+	sendTestEvent(t, conn, `{"event_name":"PlayerLogin","world_id":"1","character_id":"1"}`)
+
+	select {
+	case event := <-received:
+		login, ok := event.(*PlayerLoginEvent)
+		if !ok || login.CharacterID != "1" {
+			t.Fatalf("received %+v, want the login of character 1", event)
+		}
+	case <-time.After(eventStreamTestTimeout):
+		t.Fatal("timed out waiting for the login event")
+	}
+
+	select {
+	case event := <-received:
+		t.Fatalf("received unexpected event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventStreamResubscribesAfterReconnect(t *testing.T) {
+	server := newFakeEventStreamServer()
+	defer server.close()
+
+	stream := newEventStream(server.uri())
+
+	unsubscribeLogins := stream.subscribe(eventFilter{Worlds: []string{"1"}, EventNames: []string{eventPlayerLogin}}, func(planetsideEvent) {})
+	defer unsubscribeLogins()
+
+	conn := server.nextConnection(t)
+	expectCommand(t, server.nextCommand(t), "subscribe", nil, []string{"1"}, []string{eventPlayerLogin})
+
+	unsubscribeCharacter := stream.subscribe(eventFilter{Characters: []string{"5"}, EventNames: []string{eventPlayerLogout}}, func(planetsideEvent) {})
+	defer unsubscribeCharacter()
+
+	expectCommand(t, server.nextCommand(t), "subscribe", []string{"5"}, nil, []string{eventPlayerLogout})
+
+	conn.Close()
+
+	server.nextConnection(t)
+	expectCommand(t, server.nextCommand(t), "subscribe", []string{"5"}, []string{"1"}, []string{eventPlayerLogin, eventPlayerLogout})
+
+	if reconnects := stream.stats()["Reconnects"]; reconnects != "1" {
+		t.Errorf("stats report %s reconnects, want 1", reconnects)
+	}
+}
+
+func TestEventStreamReferenceCountedUnsubscribe(t *testing.T) {
+	server := newFakeEventStreamServer()
+	defer server.close()
+
+	stream := newEventStream(server.uri())
+
+	unsubscribeFirst := stream.subscribe(eventFilter{Worlds: []string{"1", "17"}, EventNames: []string{eventMetagameEvent}}, func(planetsideEvent) {})
+	defer unsubscribeFirst()
+
+	server.nextConnection(t)
+	expectCommand(t, server.nextCommand(t), "subscribe", nil, []string{"1", "17"}, []string{eventMetagameEvent})
+
+	// Everything the second subscriber needs is already subscribed, so nothing is sent.
+	unsubscribeSecond := stream.subscribe(eventFilter{Worlds: []string{"1"}, EventNames: []string{eventMetagameEvent}}, func(planetsideEvent) {})
+	defer unsubscribeSecond()
+
+	server.expectNoCommand(t)
+
+	// World 1 and the event name are still used by the second subscriber.
+	unsubscribeFirst()
+	expectCommand(t, server.nextCommand(t), "clearSubscribe", nil, []string{"17"}, nil)
+
+	// Unsubscribing twice must not release the keys again.
+	unsubscribeFirst()
+	server.expectNoCommand(t)
+
+	unsubscribeSecond()
+	expectCommand(t, server.nextCommand(t), "clearSubscribe", nil, []string{"1"}, []string{eventMetagameEvent})
+}
@@ -0,0 +1,352 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	// feedFlushInterval batches activity so a channel gets at most one message per interval.
+	feedFlushInterval = time.Minute
+	// feedRefreshInterval is how often outfit member lists are reloaded to pick up recruits.
+	feedRefreshInterval = 30 * time.Minute
+	feedMessageLimit    = 2000
+)
+
+var channelMentionPattern = regexp.MustCompile(`^<#([0-9]+)>$`)
+
+// watchedOutfit is an outfit whose members' logins and logouts are being followed.
+type watchedOutfit struct {
+	platform    string
+	outfitID    string
+	members     map[string]string
+	unsubscribe func()
+	generation  int
+}
+
+type outfitFeedWatcher struct {
+	sync.Mutex
+	outfits map[string]*watchedOutfit
+	pending map[string][]string
+	started bool
+}
+
+func newOutfitFeedWatcher() *outfitFeedWatcher {
+	return &outfitFeedWatcher{
+		outfits: make(map[string]*watchedOutfit),
+		pending: make(map[string][]string),
+	}
+}
+
+func (w *outfitFeedWatcher) queue(channelID string, line string) {
+	w.Lock()
+	w.pending[channelID] = append(w.pending[channelID], line)
+	w.Unlock()
+}
+
+func (w *outfitFeedWatcher) takePending() map[string][]string {
+	w.Lock()
+	defer w.Unlock()
+
+	pending := w.pending
+	w.pending = make(map[string][]string)
+
+	return pending
+}
+
+// startFeeds restores saved outfit feeds and starts the flush and refresh loops.
+func (p *planetsidetwoPlugin) startFeeds() {
+	p.feeds.Lock()
+	if p.feeds.started {
+		p.feeds.Unlock()
+		return
+	}
+	p.feeds.started = true
+	p.feeds.Unlock()
+
+	feeds, err := p.repository.getOutfitFeeds()
+	if err != nil {
+		log.Printf("Failed to get outfit feeds: %s", err)
+	}
+
+	for _, feed := range feeds {
+		p.watchOutfit(feed.Platform, feed.OutfitID)
+	}
+
+	go p.runFeedFlush()
+	go p.runFeedRefresh()
+}
+
+func (p *planetsidetwoPlugin) runFeedFlush() {
+	ticker := time.NewTicker(feedFlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		session := p.session()
+		if session == nil {
+			continue
+		}
+
+		for channelID, lines := range p.feeds.takePending() {
+			for _, content := range chunkLines(lines, feedMessageLimit) {
+				if _, err := session.ChannelMessageSend(channelID, content); err != nil {
+					log.Printf("Failed to send outfit feed to channel '%s': %s", channelID, err)
+				}
+			}
+		}
+	}
+}
+
+func (p *planetsidetwoPlugin) runFeedRefresh() {
+	ticker := time.NewTicker(feedRefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.feeds.Lock()
+		outfits := make([]*watchedOutfit, 0, len(p.feeds.outfits))
+		for _, outfit := range p.feeds.outfits {
+			outfits = append(outfits, outfit)
+		}
+		p.feeds.Unlock()
+
+		for _, outfit := range outfits {
+			p.refreshOutfitMembers(outfit.platform, outfit.outfitID)
+		}
+	}
+}
+
+// watchOutfit starts following an outfit's members if no other feed already does.
+func (p *planetsidetwoPlugin) watchOutfit(platform string, outfitID string) {
+	p.feeds.Lock()
+	_, ok := p.feeds.outfits[platform+"/"+outfitID]
+	p.feeds.Unlock()
+
+	if !ok {
+		p.refreshOutfitMembers(platform, outfitID)
+	}
+}
+
+// refreshOutfitMembers reloads the outfit's members and moves its event subscription over
+// to them. Events still queued for the previous subscription are ignored by generation.
+func (p *planetsidetwoPlugin) refreshOutfitMembers(platform string, outfitID string) {
+	members, err := p.dataSource.GetOutfitMembers(outfitID, platform)
+	if err != nil {
+		log.Printf("Failed to get members of outfit '%s': %s", outfitID, err)
+		return
+	}
+
+	memberNames := make(map[string]string, len(members))
+	characterIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		memberNames[member.CharacterId] = member.Name
+		characterIDs = append(characterIDs, member.CharacterId)
+	}
+
+	key := platform + "/" + outfitID
+
+	p.feeds.Lock()
+	defer p.feeds.Unlock()
+
+	outfit, ok := p.feeds.outfits[key]
+	if !ok {
+		outfit = &watchedOutfit{
+			platform: platform,
+			outfitID: outfitID,
+		}
+		p.feeds.outfits[key] = outfit
+	}
+
+	previous := outfit.unsubscribe
+	outfit.members = memberNames
+	outfit.generation++
+	generation := outfit.generation
+
+	// An empty filter would follow every character on the platform. Facility captures and
+	// defenses are followed as well so that ps2online can tell which continent members are
+	// fighting on. Deaths are not: Census sends every subscribed event name for the alert
+	// subscriptions' worlds too, which for deaths would be all of the fighting on them.
+	outfit.unsubscribe = func() {}
+	if len(characterIDs) > 0 {
+		filter := eventFilter{
+			Characters: characterIDs,
+			EventNames: []string{eventPlayerLogin, eventPlayerLogout, eventPlayerFacilityCapture, eventPlayerFacilityDefend},
+		}
+
+		outfit.unsubscribe = p.events.get(platform).subscribe(filter, func(event planetsideEvent) {
+			p.activity.record(event)
+			p.queueOutfitActivity(outfit, generation, event)
+		})
+	}
+
+	if previous != nil {
+		previous()
+	}
+}
+
+// unwatchOutfit stops following an outfit once no channel has a feed for it.
+func (p *planetsidetwoPlugin) unwatchOutfit(platform string, outfitID string) {
+	feeds, err := p.repository.getOutfitFeedChannels(platform, outfitID)
+	if err != nil || len(feeds) > 0 {
+		return
+	}
+
+	key := platform + "/" + outfitID
+
+	p.feeds.Lock()
+	defer p.feeds.Unlock()
+
+	if outfit, ok := p.feeds.outfits[key]; ok {
+		outfit.unsubscribe()
+		delete(p.feeds.outfits, key)
+	}
+}
+
+func (p *planetsidetwoPlugin) queueOutfitActivity(outfit *watchedOutfit, generation int, event planetsideEvent) {
+	var characterID, action string
+	var timestamp time.Time
+	switch e := event.(type) {
+	case *PlayerLoginEvent:
+		characterID, action, timestamp = e.CharacterID, "logged in", e.Time()
+	case *PlayerLogoutEvent:
+		characterID, action, timestamp = e.CharacterID, "logged out", e.Time()
+	default:
+		return
+	}
+
+	p.feeds.Lock()
+	current := outfit.generation == generation
+	name, ok := outfit.members[characterID]
+	p.feeds.Unlock()
+
+	if !current || !ok {
+		return
+	}
+
+	feeds, err := p.repository.getOutfitFeedChannels(outfit.platform, outfit.outfitID)
+	if err != nil {
+		log.Printf("Failed to get feeds for outfit '%s': %s", outfit.outfitID, err)
+		return
+	}
+
+	for _, feed := range feeds {
+		p.feeds.queue(feed.ChannelID, fmt.Sprintf("`%s` [%s] **%s** %s", timestamp.Format("15:04"), feed.OutfitAlias, name, action))
+	}
+}
+
+func (p *planetsidetwoPlugin) runFeedCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Outfit feeds can only be configured in a server.")
+		p.RUnlock()
+		return
+	}
+
+	channelID := message.Channel()
+	if match := channelMentionPattern.FindStringSubmatch(args["channel"]); match != nil {
+		target, err := client.Channel(match[1])
+		if err != nil || target.GuildID != channel.GuildID {
+			p.RLock()
+			client.SendMessage(message.Channel(), "That channel is not in this server.")
+			p.RUnlock()
+			return
+		}
+		channelID = match[1]
+	}
+
+	if strings.ToLower(args["action"]) == "remove" {
+		feed, err := p.repository.removeOutfitFeed(channelID, platform, args["outfitAlias"])
+		if err == nil && feed != nil {
+			p.unwatchOutfit(feed.Platform, feed.OutfitID)
+		}
+
+		p.RLock()
+
+		if err != nil {
+			client.SendMessage(message.Channel(), "Failed to remove outfit feed.")
+		} else if feed == nil {
+			client.SendMessage(message.Channel(), fmt.Sprintf("<#%s> has no feed for [%s].", channelID, args["outfitAlias"]))
+		} else {
+			client.SendMessage(message.Channel(), fmt.Sprintf("Removed the [%s] feed from <#%s>!", feed.OutfitAlias, channelID))
+		}
+
+		p.RUnlock()
+		return
+	}
+
+	outfit, err := p.dataSource.GetOutfit(args["outfitAlias"], platform)
+	if err == nil && outfit.OutfitId == "" {
+		err = fmt.Errorf("Outfit '%s' not found", args["outfitAlias"])
+	}
+
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	userID := message.UserID()
+
+	err = p.repository.addOutfitFeed(&outfitFeed{
+		ChannelID:   channelID,
+		Platform:    platform,
+		OutfitID:    outfit.OutfitId,
+		OutfitAlias: outfit.Alias,
+		GuildID:     channel.GuildID,
+		CreatedBy:   &userID,
+	})
+	if err == nil {
+		p.watchOutfit(platform, outfit.OutfitId)
+	}
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to add outfit feed.")
+	} else {
+		client.SendMessage(message.Channel(), fmt.Sprintf("Posting [%s] %s logins and logouts in <#%s>!", outfit.Alias, outfit.Name, channelID))
+	}
+
+	p.RUnlock()
+}
+
+// chunkLines joins lines into as few messages as possible without exceeding limit characters each.
+func chunkLines(lines []string, limit int) []string {
+	chunks := make([]string, 0, 1)
+	current := ""
+
+	for _, line := range lines {
+		if current != "" && len(current)+len(line)+1 > limit {
+			chunks = append(chunks, current)
+			current = ""
+		}
+
+		if current != "" {
+			current += "\n"
+		}
+		current += line
+	}
+
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
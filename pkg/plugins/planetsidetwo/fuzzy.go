@@ -0,0 +1,170 @@
+package planetsidetwoplugin
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// fuzzyConfidentScore is the similarity above which a match is used without asking.
+	fuzzyConfidentScore = 0.9
+	// fuzzyMinimumScore and fuzzyMinimumLead let a weaker best match through when it clearly beats the runner-up.
+	fuzzyMinimumScore = 0.6
+	fuzzyMinimumLead  = 0.15
+	// fuzzyCandidateScore is the similarity a cataloged weapon needs to be offered as a candidate.
+	fuzzyCandidateScore = 0.5
+)
+
+type weaponCandidate struct {
+	weapon *PlanetsideWeaponSearchResult
+	score  float64
+}
+
+// rankWeaponCandidates scores every candidate against the query and returns them best first, without duplicate names.
+func rankWeaponCandidates(query string, candidates []*PlanetsideWeaponSearchResult) []*weaponCandidate {
+	normalizedQuery := normalizeSearchText(query)
+
+	seen := make(map[string]bool)
+	ranked := make([]*weaponCandidate, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		name := normalizeSearchText(candidate.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		ranked = append(ranked, &weaponCandidate{
+			weapon: candidate,
+			score:  searchSimilarity(normalizedQuery, name),
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	return ranked
+}
+
+// closeWeaponCandidates drops the ranked candidates scoring below minimumScore.
+func closeWeaponCandidates(ranked []*weaponCandidate, minimumScore float64) []*weaponCandidate {
+	for i, candidate := range ranked {
+		if candidate.score < minimumScore {
+			return ranked[:i]
+		}
+	}
+
+	return ranked
+}
+
+// isUnambiguousMatch reports whether the best ranked candidate can be used without asking the user.
+func isUnambiguousMatch(ranked []*weaponCandidate) bool {
+	if len(ranked) == 0 {
+		return false
+	}
+
+	best := ranked[0].score
+	if len(ranked) == 1 {
+		return best >= fuzzyMinimumScore
+	}
+
+	runnerUp := ranked[1].score
+
+	return (best >= fuzzyConfidentScore && best > runnerUp) || (best >= fuzzyMinimumScore && best-runnerUp >= fuzzyMinimumLead)
+}
+
+// searchSimilarity is the better of the whole-string edit similarity and the share of query words found in the name.
+func searchSimilarity(query string, name string) float64 {
+	if query == name {
+		return 1
+	}
+
+	similarity := editSimilarity(query, name)
+
+	queryWords := strings.Fields(query)
+	if len(queryWords) == 0 {
+		return similarity
+	}
+
+	nameWords := strings.Fields(name)
+	matched := 0.0
+	for _, queryWord := range queryWords {
+		bestWord := 0.0
+		for _, nameWord := range nameWords {
+			if wordSimilarity := editSimilarity(queryWord, nameWord); wordSimilarity > bestWord {
+				bestWord = wordSimilarity
+			}
+		}
+		matched += bestWord
+	}
+
+	// Word matches are slightly discounted so an exact name always beats a name that merely contains the query.
+	wordScore := matched / float64(len(queryWords)) * 0.95
+	if wordScore > similarity {
+		return wordScore
+	}
+
+	return similarity
+}
+
+// editSimilarity turns the Levenshtein distance into a score between 0 and 1.
+func editSimilarity(a string, b string) float64 {
+	ar, br := []rune(a), []rune(b)
+
+	longest := len(ar)
+	if len(br) > longest {
+		longest = len(br)
+	}
+
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshteinDistance(ar, br))/float64(longest)
+}
+
+func levenshteinDistance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func normalizeSearchText(text string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, text)
+
+	return strings.Join(strings.Fields(normalized), " ")
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
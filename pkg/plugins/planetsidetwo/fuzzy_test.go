@@ -0,0 +1,147 @@
+package planetsidetwoplugin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Gauss SAW", "gauss saw"},
+		{"  NS-11A  ", "ns 11a"},
+		{"T1 Cycler (AE)", "t1 cycler ae"},
+		{"---", ""},
+	}
+
+	for _, test := range tests {
+		if actual := normalizeSearchText(test.text); actual != test.expected {
+			t.Errorf("normalizeSearchText(%q) = %q, want %q", test.text, actual, test.expected)
+		}
+	}
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"", "", 0},
+		{"saw", "", 3},
+		{"", "saw", 3},
+		{"gauss", "gauss", 0},
+		{"gaus", "gauss", 1},
+		{"kitten", "sitting", 3},
+		{"orion", "onrio", 2},
+	}
+
+	for _, test := range tests {
+		if actual := levenshteinDistance([]rune(test.a), []rune(test.b)); actual != test.expected {
+			t.Errorf("levenshteinDistance(%q, %q) = %d, want %d", test.a, test.b, actual, test.expected)
+		}
+	}
+}
+
+func TestSearchSimilarity(t *testing.T) {
+	tests := []struct {
+		query    string
+		name     string
+		expected float64
+	}{
+		{"gauss saw", "gauss saw", 1},
+		{"", "", 1},
+		// Every query word is in the name, discounted below an exact match.
+		{"saw", "gauss saw", 0.95},
+		{"gauss saw", "gauss saw s", 0.95},
+		// A typo scores by edit distance when that is better than the word match.
+		{"gaus saw", "gauss saw", 1 - 1.0/9},
+	}
+
+	for _, test := range tests {
+		if actual := searchSimilarity(test.query, test.name); actual != test.expected {
+			t.Errorf("searchSimilarity(%q, %q) = %v, want %v", test.query, test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestRankWeaponCandidates(t *testing.T) {
+	tests := []struct {
+		query      string
+		candidates []string
+		expected   []string
+	}{
+		{"gauss saw", []string{"Gauss SAW S", "NS-11A", "Gauss SAW", "gauss-saw"}, []string{"Gauss SAW", "Gauss SAW S", "NS-11A"}},
+		{"orion", []string{"Orion VS54", "Onrio", "Orion"}, []string{"Orion", "Orion VS54", "Onrio"}},
+		{"anything", nil, nil},
+	}
+
+	for _, test := range tests {
+		candidates := make([]*PlanetsideWeaponSearchResult, 0, len(test.candidates))
+		for _, name := range test.candidates {
+			candidates = append(candidates, &PlanetsideWeaponSearchResult{Name: name})
+		}
+
+		ranked := rankWeaponCandidates(test.query, candidates)
+
+		names := make([]string, 0, len(ranked))
+		for _, candidate := range ranked {
+			names = append(names, candidate.weapon.Name)
+		}
+
+		if strings.Join(names, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("rankWeaponCandidates(%q) ranked %v, want %v", test.query, names, test.expected)
+		}
+	}
+}
+
+func scoredCandidates(scores ...float64) []*weaponCandidate {
+	ranked := make([]*weaponCandidate, 0, len(scores))
+	for _, score := range scores {
+		ranked = append(ranked, &weaponCandidate{weapon: &PlanetsideWeaponSearchResult{}, score: score})
+	}
+
+	return ranked
+}
+
+func TestIsUnambiguousMatch(t *testing.T) {
+	tests := []struct {
+		scores   []float64
+		expected bool
+	}{
+		{nil, false},
+		{[]float64{0.6}, true},
+		{[]float64{0.59}, false},
+		{[]float64{1, 0.95}, true},
+		{[]float64{0.95, 0.95}, false},
+		{[]float64{0.8, 0.65}, true},
+		{[]float64{0.8, 0.7}, false},
+		{[]float64{0.55, 0.1}, false},
+	}
+
+	for _, test := range tests {
+		if actual := isUnambiguousMatch(scoredCandidates(test.scores...)); actual != test.expected {
+			t.Errorf("isUnambiguousMatch(%v) = %t, want %t", test.scores, actual, test.expected)
+		}
+	}
+}
+
+func TestCloseWeaponCandidates(t *testing.T) {
+	tests := []struct {
+		scores   []float64
+		expected int
+	}{
+		{nil, 0},
+		{[]float64{0.9, 0.7, 0.5}, 3},
+		{[]float64{0.9, 0.49, 0.3}, 1},
+		{[]float64{0.4, 0.3}, 0},
+	}
+
+	for _, test := range tests {
+		if actual := closeWeaponCandidates(scoredCandidates(test.scores...), fuzzyCandidateScore); len(actual) != test.expected {
+			t.Errorf("closeWeaponCandidates(%v) kept %d candidates, want %d", test.scores, len(actual), test.expected)
+		}
+	}
+}
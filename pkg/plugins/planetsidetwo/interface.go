@@ -24,6 +24,8 @@ type PlanetsideCharacter struct {
 	IVIScore             int     `json:"iviScore"`
 	IVIKillDeathRatio    float32 `json:"iviKillDeathRatio"`
 	Prestige             int     `json:"prestige"`
+	Platform             string  `json:"-"`
+	Stale                bool    `json:"-"`
 }
 
 type PlanetsideCharacterWeapon struct {
@@ -45,12 +47,27 @@ type PlanetsideCharacterWeapon struct {
 	HeadshotRatioGrade  string  `json:"headshotRatioGrade"`
 	KillsPerHourGrade   string  `json:"killsPerHourGrade"`
 	AccuracyGrade       string  `json:"accuracyGrade"`
+	Stale               bool    `json:"-"`
+}
+
+type PlanetsideOutfitMember struct {
+	CharacterId string `json:"characterId"`
+	Name        string `json:"name"`
+	Rank        string `json:"rank"`
+	RankOrdinal int    `json:"rankOrdinal"`
+	MemberSince string `json:"memberSinceDate"`
+	LastLogin   string `json:"lastLoginDate"`
+	BattleRank  int    `json:"battleRank"`
+	Prestige    int    `json:"prestige"`
+	Online      bool   `json:"online"`
+	ClassName   string `json:"className"`
 }
 
 type PlanetsideOutfit struct {
 	OutfitId       string `json:"outfitId"`
 	Name           string `json:"name"`
 	Alias          string `json:"alias"`
+	FactionId      int    `json:"factionId"`
 	FactionName    string `json:"factionName"`
 	FactionImageId int    `json:"factionImageId"`
 	WorldName      string `json:"worldName"`
@@ -59,6 +76,7 @@ type PlanetsideOutfit struct {
 	Activity7Days  int    `json:"activity7Days"`
 	Activity30Days int    `json:"activity30Days"`
 	Activity90Days int    `json:"activity90Days"`
+	Stale          bool   `json:"-"`
 }
 
 type PlanetsideWeapon struct {
@@ -90,6 +108,7 @@ type PlanetsideWeapon struct {
 	AimAcc                 *PlanetsideWeaponAccuracyState `json:"aimAcc,omitempty"`
 	IsVehicleWeapon        bool                           `json:"isVehicleWeapon"`
 	DamageRadius           int                            `json:"damageRadius,omitempty"`
+	Stale                  bool                           `json:"-"`
 }
 
 type PlanetsideWeaponAccuracyState struct {
@@ -99,3 +118,26 @@ type PlanetsideWeaponAccuracyState struct {
 	Running       float32 `json:"running,omitempty"`
 	Cof           float32 `json:"cof,omitempty"`
 }
+
+type PlanetsideWeaponSearchResult struct {
+	ItemID    int    `json:"id"`
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	FactionID int    `json:"factionId,omitempty"`
+}
+
+// PlanetsideWorldState is a world's current population and continent territory, keyed by faction ID.
+type PlanetsideWorldState struct {
+	WorldID    string                      `json:"worldId"`
+	Name       string                      `json:"name"`
+	Population map[int]int                 `json:"population"`
+	Continents []*PlanetsideContinentState `json:"continents"`
+	Stale      bool                        `json:"-"`
+}
+
+type PlanetsideContinentState struct {
+	ZoneID    string          `json:"zoneId"`
+	Name      string          `json:"name"`
+	IsOpen    bool            `json:"isOpen"`
+	Territory map[int]float64 `json:"territory"`
+}
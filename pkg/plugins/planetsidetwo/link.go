@@ -0,0 +1,183 @@
+package planetsidetwoplugin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+var userMentionPattern = regexp.MustCompile(`^<@!?([0-9]+)>$`)
+
+// linkedUserReference reports whether reference points at a Discord user rather than a
+// character name, returning that user's ID. An empty reference and 'me' mean the invoker.
+func linkedUserReference(message discordgobot.Message, reference string) (string, bool) {
+	reference = strings.TrimSpace(reference)
+
+	if reference == "" || strings.ToLower(reference) == "me" {
+		return message.UserID(), true
+	}
+
+	if match := userMentionPattern.FindStringSubmatch(reference); match != nil {
+		return match[1], true
+	}
+
+	return "", false
+}
+
+// lookupCharacter fetches the character named by reference, resolving user references
+// through the character linked to that user.
+func (p *planetsidetwoPlugin) lookupCharacter(message discordgobot.Message, reference string, platform string) (*PlanetsideCharacter, error) {
+	userID, isUserReference := linkedUserReference(message, reference)
+	if !isUserReference {
+		return p.dataSource.GetCharacter(reference, platform)
+	}
+
+	link, err := p.repository.getCharacterLink(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if link == nil {
+		if userID == message.UserID() {
+			return nil, errors.New("You have not linked a character. Use ps2link <character name> to link one.")
+		}
+		return nil, fmt.Errorf("<@%s> has not linked a character.", userID)
+	}
+
+	character, err := p.dataSource.GetCharacterByID(link.CharacterID, link.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	if character.Name != "" && character.Name != link.CharacterName {
+		p.repository.updateCharacterLink(userID, link.CharacterID, character.Name, link.Platform)
+	}
+
+	return character, nil
+}
+
+func (p *planetsidetwoPlugin) runLinkCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	character, err := p.dataSource.GetCharacter(args["characterName"], platform)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	err = p.repository.updateCharacterLink(message.UserID(), character.CharacterId, character.Name, platform)
+	if err == nil {
+		p.addLinkToGuild(client, message)
+		go p.trackCharacter(character)
+	}
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to link character.")
+	} else {
+		client.SendMessage(message.Channel(), fmt.Sprintf("Linked %s (%s) to your account!", character.Name, platformDisplayName(platform)))
+	}
+
+	p.RUnlock()
+}
+
+func (p *planetsidetwoPlugin) runUnlinkCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	message := payload.Message
+
+	removed, err := p.repository.deleteCharacterLink(message.UserID())
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to unlink character.")
+	} else if !removed {
+		client.SendMessage(message.Channel(), "You have not linked a character.")
+	} else {
+		client.SendMessage(message.Channel(), "Character unlinked!")
+	}
+
+	p.RUnlock()
+}
+
+// addLinkToGuild lists the invoker's link in the server the message was sent in. Links are stored
+// per server, since the member list in the bot's state is not complete for large servers.
+func (p *planetsidetwoPlugin) addLinkToGuild(client *discordgobot.DiscordClient, message discordgobot.Message) {
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		return
+	}
+
+	err = p.repository.addCharacterLinkGuild(message.UserID(), channel.GuildID)
+	if err != nil {
+		log.Printf("Failed to add link for '%s' to guild '%s': %s", message.UserID(), channel.GuildID, err)
+	}
+}
+
+func (p *planetsidetwoPlugin) runListLinksCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	message := payload.Message
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Links can only be listed in a server.")
+		p.RUnlock()
+		return
+	}
+
+	links, err := p.repository.getGuildCharacterLinks(channel.GuildID)
+	if err != nil {
+		log.Printf("Failed to get linked characters for guild '%s': %s", channel.GuildID, err)
+		p.RLock()
+		client.SendMessage(message.Channel(), "Failed to get linked characters.")
+		p.RUnlock()
+		return
+	}
+
+	if len(links) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), "No one has linked a character in this server. Use ps2link <character name> here to link yours.")
+		p.RUnlock()
+		return
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return strings.ToLower(links[i].CharacterName) < strings.ToLower(links[j].CharacterName)
+	})
+
+	lines := make([]string, len(links))
+	for i, link := range links {
+		lines[i] = fmt.Sprintf("<@%s>: %s (%s)", link.UserID, link.CharacterName, platformDisplayName(link.Platform))
+	}
+
+	title := "Linked characters in this server"
+	if guild, err := client.Guild(channel.GuildID); err == nil {
+		title = fmt.Sprintf("Linked characters in %s", guild.Name)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Color:       p.embedColor(client, message.Channel(), 0),
+		Description: joinLinesWithinLimit(lines, embedDescriptionLimit),
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
@@ -0,0 +1,398 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMetadataRefreshInterval is how often metadata is refreshed from Census once PS2MetadataRefresh is set.
+const defaultMetadataRefreshInterval = 24 * time.Hour
+
+type factionMetadata struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Color   string `json:"color"`
+	ImageID int    `json:"imageId"`
+	color   int
+}
+
+type worldMetadata struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+	Region   string `json:"region"`
+}
+
+type continentMetadata struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type metadataFile struct {
+	Factions   []*factionMetadata   `json:"factions"`
+	Worlds     []*worldMetadata     `json:"worlds"`
+	Continents []*continentMetadata `json:"continents"`
+}
+
+// metadataRegistry holds the display names, codes, colors and image IDs of the game's
+// factions, worlds and continents.
+type metadataRegistry struct {
+	sync.RWMutex
+	factions   map[int]*factionMetadata
+	worlds     map[string]*worldMetadata
+	continents map[int]*continentMetadata
+}
+
+var planetsideMetadata = mustLoadMetadata(defaultMetadata)
+
+func mustLoadMetadata(data string) *metadataRegistry {
+	registry, err := loadMetadata([]byte(data))
+	if err != nil {
+		panic(fmt.Sprintf("planetsidetwoplugin: invalid metadata: %s", err))
+	}
+
+	return registry
+}
+
+func loadMetadata(data []byte) (*metadataRegistry, error) {
+	var file metadataFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &metadataRegistry{
+		factions:   make(map[int]*factionMetadata, len(file.Factions)),
+		worlds:     make(map[string]*worldMetadata, len(file.Worlds)),
+		continents: make(map[int]*continentMetadata, len(file.Continents)),
+	}
+
+	for _, faction := range file.Factions {
+		color, err := strconv.ParseInt(strings.TrimPrefix(faction.Color, "#"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("faction %d has invalid color '%s'", faction.ID, faction.Color)
+		}
+		faction.color = int(color)
+		registry.factions[faction.ID] = faction
+	}
+
+	for _, world := range file.Worlds {
+		if _, ok := censusNamespaces[world.Platform]; !ok {
+			return nil, fmt.Errorf("world %s has unknown platform '%s'", world.ID, world.Platform)
+		}
+		registry.worlds[world.ID] = world
+	}
+
+	for _, continent := range file.Continents {
+		registry.continents[continent.ID] = continent
+	}
+
+	return registry, nil
+}
+
+func (r *metadataRegistry) faction(factionID int) (*factionMetadata, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	faction, ok := r.factions[factionID]
+	return faction, ok
+}
+
+// factionIDs returns every known faction in ID order.
+func (r *metadataRegistry) factionIDs() []int {
+	r.RLock()
+	defer r.RUnlock()
+
+	factionIDs := make([]int, 0, len(r.factions))
+	for factionID := range r.factions {
+		factionIDs = append(factionIDs, factionID)
+	}
+	sort.Ints(factionIDs)
+
+	return factionIDs
+}
+
+func (r *metadataRegistry) world(worldID string) (*worldMetadata, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	world, ok := r.worlds[worldID]
+	return world, ok
+}
+
+func (r *metadataRegistry) findWorld(name string) (*worldMetadata, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, world := range r.worlds {
+		if strings.EqualFold(world.Name, name) {
+			return world, true
+		}
+	}
+
+	return nil, false
+}
+
+// platformWorldIDs returns the worlds on a platform in ID order.
+func (r *metadataRegistry) platformWorldIDs(platform string) []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	worldIDs := make([]string, 0)
+	for worldID, world := range r.worlds {
+		if world.Platform == platform {
+			worldIDs = append(worldIDs, worldID)
+		}
+	}
+
+	sort.Slice(worldIDs, func(i, j int) bool {
+		return censusAtoi(worldIDs[i]) < censusAtoi(worldIDs[j])
+	})
+
+	return worldIDs
+}
+
+func (r *metadataRegistry) continent(continentID int) (*continentMetadata, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	continent, ok := r.continents[continentID]
+	return continent, ok
+}
+
+// continentIDs returns every known continent in ID order.
+func (r *metadataRegistry) continentIDs() []int {
+	r.RLock()
+	defer r.RUnlock()
+
+	continentIDs := make([]int, 0, len(r.continents))
+	for continentID := range r.continents {
+		continentIDs = append(continentIDs, continentID)
+	}
+	sort.Ints(continentIDs)
+
+	return continentIDs
+}
+
+type censusMetadataFaction struct {
+	FactionID string                `json:"faction_id"`
+	Name      censusLocalizedString `json:"name"`
+	CodeTag   string                `json:"code_tag"`
+	ImageID   string                `json:"image_id"`
+}
+
+type censusMetadataWorld struct {
+	WorldID string                `json:"world_id"`
+	Name    censusLocalizedString `json:"name"`
+}
+
+type censusMetadataZone struct {
+	ZoneID string                `json:"zone_id"`
+	Name   censusLocalizedString `json:"name"`
+}
+
+// refresh merges the metadata Census publishes into the registry. Census knows nothing of
+// colors or regions, so those stay as bundled, and new factions get the house color until the
+// bundled data catches up. Continents are only renamed since Census lists every zone.
+func (r *metadataRegistry) refresh(census *censusDataSource) error {
+	query := url.Values{}
+	query.Set("c:limit", "100")
+
+	var factions []censusMetadataFaction
+	err := census.get(platformPC, "faction", query, &factions)
+	if err != nil {
+		return err
+	}
+
+	worlds := make(map[string][]censusMetadataWorld, len(censusNamespaces))
+	for platform := range censusNamespaces {
+		var records []censusMetadataWorld
+		err = census.get(platform, "world", query, &records)
+		if err != nil {
+			return err
+		}
+		worlds[platform] = records
+	}
+
+	continentIDs := make([]string, 0)
+	for _, continentID := range r.continentIDs() {
+		continentIDs = append(continentIDs, strconv.Itoa(continentID))
+	}
+
+	zoneQuery := url.Values{}
+	zoneQuery.Set("zone_id", strings.Join(continentIDs, ","))
+
+	var zones []censusMetadataZone
+	err = census.get(platformPC, "zone", zoneQuery, &zones)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for _, record := range factions {
+		factionID := censusAtoi(record.FactionID)
+		if factionID == 0 || record.Name.En == "" {
+			continue
+		}
+
+		faction, ok := r.factions[factionID]
+		if !ok {
+			faction = &factionMetadata{ID: factionID, Code: record.CodeTag, color: defaultHouseColor}
+			log.Printf("Added faction %d (%s) from Census", factionID, record.Name.En)
+		}
+
+		updated := *faction
+		updated.Name = record.Name.En
+		if imageID := censusAtoi(record.ImageID); imageID != 0 {
+			updated.ImageID = imageID
+		}
+		r.factions[factionID] = &updated
+	}
+
+	for platform, records := range worlds {
+		for _, record := range records {
+			if record.WorldID == "" || record.Name.En == "" {
+				continue
+			}
+
+			world, ok := r.worlds[record.WorldID]
+			if !ok {
+				world = &worldMetadata{ID: record.WorldID, Platform: platform}
+				log.Printf("Added world %s (%s) from Census", record.WorldID, record.Name.En)
+			}
+
+			updated := *world
+			updated.Name = record.Name.En
+			r.worlds[record.WorldID] = &updated
+		}
+	}
+
+	for _, record := range zones {
+		continentID := censusAtoi(record.ZoneID)
+		if continent, ok := r.continents[continentID]; ok && record.Name.En != "" {
+			r.continents[continentID] = &continentMetadata{ID: continent.ID, Name: record.Name.En}
+		}
+	}
+
+	return nil
+}
+
+// startMetadataRefresh keeps the registry current when PS2MetadataRefresh names an interval
+// (or is 'true' for daily). The bundled metadata is used as is otherwise.
+func startMetadataRefresh(registry *metadataRegistry) {
+	value := os.Getenv("PS2MetadataRefresh")
+	if value == "" || value == "false" {
+		return
+	}
+
+	interval := defaultMetadataRefreshInterval
+	if value != "true" {
+		parsed, err := time.ParseDuration(value)
+		if err == nil && parsed > 0 {
+			interval = parsed
+		} else {
+			log.Printf("Invalid PS2MetadataRefresh '%s', using %s", value, defaultMetadataRefreshInterval)
+		}
+	}
+
+	census := newCensusDataSource()
+
+	for {
+		err := registry.refresh(census)
+		if err != nil {
+			log.Printf("Failed to refresh PS2 metadata: %s", err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func getFactionName(factionID int) string {
+	if faction, ok := planetsideMetadata.faction(factionID); ok {
+		return faction.Name
+	}
+
+	return "Unknown"
+}
+
+func getFactionCode(factionID int) string {
+	if faction, ok := planetsideMetadata.faction(factionID); ok {
+		return faction.Code
+	}
+
+	return "?"
+}
+
+func getFactionColor(factionID int) int {
+	if faction, ok := planetsideMetadata.faction(factionID); ok {
+		return faction.color
+	}
+
+	return defaultHouseColor
+}
+
+// findWorld returns the ID of the world with the given name, ignoring case.
+func findWorld(name string) (string, bool) {
+	if world, ok := planetsideMetadata.findWorld(name); ok {
+		return world.ID, true
+	}
+
+	return "", false
+}
+
+func worldPlatform(worldID string) string {
+	if world, ok := planetsideMetadata.world(worldID); ok {
+		return world.Platform
+	}
+
+	return platformPC
+}
+
+func worldName(worldID string) string {
+	if world, ok := planetsideMetadata.world(worldID); ok {
+		return world.Name
+	}
+
+	return "World " + worldID
+}
+
+func worldRegion(worldID string) string {
+	if world, ok := planetsideMetadata.world(worldID); ok {
+		return world.Region
+	}
+
+	return ""
+}
+
+// zoneName names the continent of a zone. Instanced zones carry the continent in their low 16 bits.
+func zoneName(zoneID string) string {
+	if continent, ok := planetsideMetadata.continent(censusAtoi(zoneID) & 0xFFFF); ok {
+		return continent.Name
+	}
+
+	return "Zone " + zoneID
+}
+
+// factionImageID prefers the image the data source reported, falling back to the faction's bundled image.
+func factionImageID(factionID int, imageID int) int {
+	if imageID != 0 {
+		return imageID
+	}
+
+	if faction, ok := planetsideMetadata.faction(factionID); ok {
+		return faction.ImageID
+	}
+
+	return 0
+}
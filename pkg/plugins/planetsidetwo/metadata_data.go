@@ -0,0 +1,29 @@
+package planetsidetwoplugin
+
+// defaultMetadata is the bundled copy of the game metadata, used until (or instead of) a refresh
+// from Census. Adding a faction, world or continent only needs an entry here.
+const defaultMetadata = `{
+	"factions": [
+		{ "id": 1, "name": "Vanu Sovereignty", "code": "VS", "color": "#6A1B9A", "imageId": 94 },
+		{ "id": 2, "name": "New Conglomerate", "code": "NC", "color": "#1565C0", "imageId": 12 },
+		{ "id": 3, "name": "Terran Republic", "code": "TR", "color": "#C62828", "imageId": 18 },
+		{ "id": 4, "name": "NS Operatives", "code": "NSO", "color": "#757575", "imageId": 1 }
+	],
+	"worlds": [
+		{ "id": "1", "name": "Connery", "platform": "pc", "region": "US West" },
+		{ "id": "10", "name": "Miller", "platform": "pc", "region": "EU" },
+		{ "id": "13", "name": "Cobalt", "platform": "pc", "region": "EU" },
+		{ "id": "17", "name": "Emerald", "platform": "pc", "region": "US East" },
+		{ "id": "19", "name": "Jaeger", "platform": "pc", "region": "Events" },
+		{ "id": "40", "name": "SolTech", "platform": "pc", "region": "Asia" },
+		{ "id": "1000", "name": "Genudine", "platform": "ps4us", "region": "US" },
+		{ "id": "2000", "name": "Ceres", "platform": "ps4eu", "region": "EU" }
+	],
+	"continents": [
+		{ "id": 2, "name": "Indar" },
+		{ "id": 4, "name": "Hossin" },
+		{ "id": 6, "name": "Amerish" },
+		{ "id": 8, "name": "Esamir" },
+		{ "id": 344, "name": "Oshur" }
+	]
+}`
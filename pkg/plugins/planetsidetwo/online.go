@@ -0,0 +1,162 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+// characterLocationTTL is how long a character's last seen continent is trusted.
+const characterLocationTTL = 30 * time.Minute
+
+type characterLocation struct {
+	zoneID string
+	seen   time.Time
+}
+
+// characterActivity remembers where characters last captured or defended a facility. Census does
+// not report a character's continent, so it is only known for characters followed on the event stream.
+type characterActivity struct {
+	sync.Mutex
+	locations map[string]*characterLocation
+}
+
+func newCharacterActivity() *characterActivity {
+	return &characterActivity{
+		locations: make(map[string]*characterLocation),
+	}
+}
+
+func (a *characterActivity) record(event planetsideEvent) {
+	a.Lock()
+	defer a.Unlock()
+
+	switch e := event.(type) {
+	case *PlayerFacilityCaptureEvent:
+		a.locations[e.CharacterID] = &characterLocation{zoneID: e.ZoneID, seen: e.Time()}
+	case *PlayerFacilityDefendEvent:
+		a.locations[e.CharacterID] = &characterLocation{zoneID: e.ZoneID, seen: e.Time()}
+	case *PlayerLogoutEvent:
+		delete(a.locations, e.CharacterID)
+	}
+}
+
+func (a *characterActivity) location(characterID string) *characterLocation {
+	a.Lock()
+	defer a.Unlock()
+
+	location, ok := a.locations[characterID]
+	if !ok {
+		return nil
+	}
+
+	if time.Since(location.seen) > characterLocationTTL {
+		delete(a.locations, characterID)
+		return nil
+	}
+
+	return location
+}
+
+func (p *planetsidetwoPlugin) runOnlineCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	outfit, err := p.dataSource.GetOutfit(args["outfitAlias"], platform)
+	if err == nil && outfit.OutfitId == "" {
+		err = fmt.Errorf("Outfit '%s' not found", args["outfitAlias"])
+	}
+
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	members, err := p.dataSource.GetOutfitOnlineMembers(outfit.OutfitId, platform)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	if len(members) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("No one from [%s] is online.", outfit.Alias))
+		p.RUnlock()
+		return
+	}
+
+	groups := make(map[string][]string)
+	for _, member := range members {
+		continent := "Unknown continent"
+		if location := p.activity.location(member.CharacterId); location != nil {
+			continent = zoneName(location.zoneID)
+		}
+
+		line := fmt.Sprintf("**%s** · %s", member.Name, formatMemberBattleRank(member))
+		if member.ClassName != "" {
+			line += " · " + member.ClassName
+		}
+
+		groups[continent] = append(groups[continent], line)
+	}
+
+	continents := make([]string, 0, len(groups))
+	for continent := range groups {
+		continents = append(continents, continent)
+	}
+
+	// Known continents first, alphabetically, with the unknown group at the end.
+	sort.Slice(continents, func(i, j int) bool {
+		iUnknown, jUnknown := strings.HasPrefix(continents[i], "Unknown"), strings.HasPrefix(continents[j], "Unknown")
+		if iUnknown != jUnknown {
+			return jUnknown
+		}
+		return continents[i] < continents[j]
+	})
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(continents))
+	for _, continent := range continents {
+		lines := groups[continent]
+		sort.Strings(lines)
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s (%d)", continent, len(lines)),
+			Value: joinLinesWithinLimit(lines, embedFieldValueLimit),
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("[%s] %s: %d online", outfit.Alias, outfit.Name, len(members)),
+		URL:    p.config.voidwellLink("ps2/outfit/%s", outfit.OutfitId),
+		Color:  p.embedColor(client, message.Channel(), outfit.FactionId),
+		Fields: fields,
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
+
+func formatMemberBattleRank(member *PlanetsideOutfitMember) string {
+	if member.Prestige > 0 {
+		return fmt.Sprintf("BR %d (ASP %d)", member.BattleRank, member.Prestige)
+	}
+
+	return fmt.Sprintf("BR %d", member.BattleRank)
+}
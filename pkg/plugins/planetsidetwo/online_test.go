@@ -0,0 +1,61 @@
+package planetsidetwoplugin
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCharacterActivity(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-characterLocationTTL-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name         string
+		payloads     []string
+		expectedZone string
+	}{
+		{"capture", []string{`{"event_name":"PlayerFacilityCapture","character_id":"1","zone_id":"2","timestamp":"` + now + `"}`}, "2"},
+		{"defend", []string{`{"event_name":"PlayerFacilityDefend","character_id":"1","zone_id":"8","timestamp":"` + now + `"}`}, "8"},
+		{
+			"latest event wins",
+			[]string{
+				`{"event_name":"PlayerFacilityCapture","character_id":"1","zone_id":"2","timestamp":"` + now + `"}`,
+				`{"event_name":"PlayerFacilityDefend","character_id":"1","zone_id":"4","timestamp":"` + now + `"}`,
+			},
+			"4",
+		},
+		{
+			"logout forgets the continent",
+			[]string{
+				`{"event_name":"PlayerFacilityCapture","character_id":"1","zone_id":"2","timestamp":"` + now + `"}`,
+				`{"event_name":"PlayerLogout","character_id":"1","timestamp":"` + now + `"}`,
+			},
+			"",
+		},
+		{"expired", []string{`{"event_name":"PlayerFacilityCapture","character_id":"1","zone_id":"2","timestamp":"` + old + `"}`}, ""},
+		{"other character", []string{`{"event_name":"PlayerFacilityCapture","character_id":"2","zone_id":"2","timestamp":"` + now + `"}`}, ""},
+		{"login", []string{`{"event_name":"PlayerLogin","character_id":"1","timestamp":"` + now + `"}`}, ""},
+	}
+
+	for _, test := range tests {
+		activity := newCharacterActivity()
+
+		for _, payload := range test.payloads {
+			event, err := parsePlanetsideEvent([]byte(payload))
+			if err != nil {
+				t.Fatalf("%s: failed to parse %s: %s", test.name, payload, err)
+			}
+			activity.record(event)
+		}
+
+		zone := ""
+		if location := activity.location("1"); location != nil {
+			zone = location.zoneID
+		}
+
+		if zone != test.expectedZone {
+			t.Errorf("%s: character 1 is on zone %q, want %q", test.name, zone, test.expectedZone)
+		}
+	}
+}
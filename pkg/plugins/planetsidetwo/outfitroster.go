@@ -0,0 +1,178 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dustin/go-humanize"
+	"github.com/lampjaw/discordgobot"
+)
+
+const outfitRosterPageSize = 15
+
+var outfitRosterSortPattern = regexp.MustCompile(`(?i)\bsort=(\S+)`)
+var outfitRosterInactivePattern = regexp.MustCompile(`(?i)--inactive[= ]([0-9]+)d?\b`)
+
+// outfitRosterSorts order members for ps2roster-view. Ties fall back to the member name.
+var outfitRosterSorts = map[string]func(a *PlanetsideOutfitMember, b *PlanetsideOutfitMember) bool{
+	"rank": func(a *PlanetsideOutfitMember, b *PlanetsideOutfitMember) bool {
+		return a.RankOrdinal < b.RankOrdinal
+	},
+	"br": func(a *PlanetsideOutfitMember, b *PlanetsideOutfitMember) bool {
+		return a.Prestige*100+a.BattleRank > b.Prestige*100+b.BattleRank
+	},
+	"lastonline": func(a *PlanetsideOutfitMember, b *PlanetsideOutfitMember) bool {
+		aLogin, _ := parseTimestamp(a.LastLogin)
+		bLogin, _ := parseTimestamp(b.LastLogin)
+		return aLogin.After(bLogin)
+	},
+	"name": func(a *PlanetsideOutfitMember, b *PlanetsideOutfitMember) bool {
+		return false
+	},
+}
+
+func (p *planetsidetwoPlugin) runOutfitRosterCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	sortName := "rank"
+	if match := outfitRosterSortPattern.FindStringSubmatch(args["options"]); match != nil {
+		sortName = strings.ToLower(match[1])
+	}
+
+	less, ok := outfitRosterSorts[sortName]
+	if !ok {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("Unknown sort '%s'. Use rank, br, lastonline or name.", sortName))
+		p.RUnlock()
+		return
+	}
+
+	inactiveDays := 0
+	if match := outfitRosterInactivePattern.FindStringSubmatch(args["options"]); match != nil {
+		inactiveDays, _ = strconv.Atoi(match[1])
+	}
+
+	outfit, err := p.dataSource.GetOutfit(args["outfitAlias"], platform)
+	if err == nil && outfit.OutfitId == "" {
+		err = fmt.Errorf("Outfit '%s' not found", args["outfitAlias"])
+	}
+
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	members, err := p.dataSource.GetOutfitMembers(outfit.OutfitId, platform)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	if inactiveDays > 0 {
+		cutoff := time.Now().UTC().AddDate(0, 0, -inactiveDays)
+
+		inactive := make([]*PlanetsideOutfitMember, 0, len(members))
+		for _, member := range members {
+			lastLogin, ok := parseTimestamp(member.LastLogin)
+			if !ok || lastLogin.Before(cutoff) {
+				inactive = append(inactive, member)
+			}
+		}
+		members = inactive
+	}
+
+	if len(members) == 0 {
+		p.RLock()
+		if inactiveDays > 0 {
+			client.SendMessage(message.Channel(), fmt.Sprintf("Every member of [%s] has logged in within the last %d days.", outfit.Alias, inactiveDays))
+		} else {
+			client.SendMessage(message.Channel(), fmt.Sprintf("[%s] has no members.", outfit.Alias))
+		}
+		p.RUnlock()
+		return
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		if less(members[i], members[j]) {
+			return true
+		}
+		if less(members[j], members[i]) {
+			return false
+		}
+		return strings.ToLower(members[i].Name) < strings.ToLower(members[j].Name)
+	})
+
+	lines := make([]string, len(members))
+	for i, member := range members {
+		lines[i] = formatOutfitMember(member)
+	}
+
+	title := fmt.Sprintf("[%s] %s members", outfit.Alias, outfit.Name)
+	if inactiveDays > 0 {
+		title = fmt.Sprintf("[%s] %s members inactive for %d days", outfit.Alias, outfit.Name, inactiveDays)
+	}
+
+	color := p.embedColor(client, message.Channel(), outfit.FactionId)
+
+	pages := paginateLines(lines, outfitRosterPageSize, func(description string) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{
+			Title:       title,
+			URL:         p.config.voidwellLink("ps2/outfit/%s", outfit.OutfitId),
+			Color:       color,
+			Description: description,
+			Footer:      createStaleFooter(outfit.Stale),
+		}
+	})
+
+	p.RLock()
+	err = p.pagination.send(client, message.Channel(), message.UserID(), pages, 0)
+	p.RUnlock()
+
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Failed to send the outfit roster.")
+		p.RUnlock()
+	}
+}
+
+func formatOutfitMember(member *PlanetsideOutfitMember) string {
+	name := member.Name
+	if name == "" {
+		name = member.CharacterId
+	}
+
+	lastOnline := "never"
+	if lastLogin, ok := parseTimestamp(member.LastLogin); ok {
+		lastOnline = humanize.Time(lastLogin)
+	}
+
+	return fmt.Sprintf("**%s** · %s · %s · last online %s", name, member.Rank, formatMemberBattleRank(member), lastOnline)
+}
+
+// parseTimestamp reads the timestamps returned by the data sources, which may omit the zone.
+func parseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil && t.Unix() > 0 {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
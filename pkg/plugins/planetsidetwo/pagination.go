@@ -0,0 +1,158 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	paginationTimeout       = 5 * time.Minute
+	paginationPreviousEmoji = "◀"
+	paginationNextEmoji     = "▶"
+)
+
+// paginatedMessage is an embed split across pages that its invoker can flip through with reactions.
+type paginatedMessage struct {
+	sync.Mutex
+	channelID string
+	messageID string
+	userID    string
+	pages     []*discordgo.MessageEmbed
+	current   int
+}
+
+type paginationManager struct {
+	sync.Mutex
+	messages     map[string]*paginatedMessage
+	handlersOnce sync.Once
+}
+
+func newPaginationManager() *paginationManager {
+	return &paginationManager{
+		messages: make(map[string]*paginatedMessage),
+	}
+}
+
+// send posts the first page and lets userID navigate the rest. Every page gets a 'Page x/y' footer.
+func (m *paginationManager) send(client *discordgobot.DiscordClient, channelID string, userID string, pages []*discordgo.MessageEmbed, start int) error {
+	if len(pages) == 0 {
+		return nil
+	}
+
+	for i, page := range pages {
+		footer := fmt.Sprintf("Page %d/%d", i+1, len(pages))
+		if page.Footer != nil && page.Footer.Text != "" {
+			footer = page.Footer.Text + " • " + footer
+		}
+		page.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+
+	if start < 0 || start >= len(pages) {
+		start = 0
+	}
+
+	if len(pages) == 1 {
+		client.SendEmbedMessage(channelID, pages[0])
+		return nil
+	}
+
+	if len(client.Sessions) == 0 {
+		return fmt.Errorf("No Discord session available")
+	}
+
+	m.registerHandlers(client)
+
+	session := client.Sessions[0]
+
+	sent, err := session.ChannelMessageSendEmbed(channelID, pages[start])
+	if err != nil {
+		return err
+	}
+
+	message := &paginatedMessage{
+		channelID: channelID,
+		messageID: sent.ID,
+		userID:    userID,
+		pages:     pages,
+		current:   start,
+	}
+
+	m.Lock()
+	m.messages[sent.ID] = message
+	m.Unlock()
+
+	time.AfterFunc(paginationTimeout, func() {
+		m.Lock()
+		delete(m.messages, sent.ID)
+		m.Unlock()
+	})
+
+	session.MessageReactionAdd(channelID, sent.ID, paginationPreviousEmoji)
+	session.MessageReactionAdd(channelID, sent.ID, paginationNextEmoji)
+
+	return nil
+}
+
+func (m *paginationManager) registerHandlers(client *discordgobot.DiscordClient) {
+	m.handlersOnce.Do(func() {
+		for _, session := range client.Sessions {
+			session.AddHandler(m.onReactionAdd)
+		}
+	})
+}
+
+func (m *paginationManager) onReactionAdd(session *discordgo.Session, reaction *discordgo.MessageReactionAdd) {
+	m.Lock()
+	message, ok := m.messages[reaction.MessageID]
+	m.Unlock()
+
+	if !ok || reaction.UserID != message.userID {
+		return
+	}
+
+	step := 0
+	switch reaction.Emoji.Name {
+	case paginationPreviousEmoji:
+		step = -1
+	case paginationNextEmoji:
+		step = 1
+	default:
+		return
+	}
+
+	// Removing the reaction lets the user press it again; this needs Manage Messages and is best effort.
+	session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.Name, reaction.UserID)
+
+	message.Lock()
+	defer message.Unlock()
+
+	next := message.current + step
+	if next < 0 || next >= len(message.pages) {
+		return
+	}
+
+	_, err := session.ChannelMessageEditEmbed(message.channelID, message.messageID, message.pages[next])
+	if err == nil {
+		message.current = next
+	}
+}
+
+// paginateLines splits lines into pages of at most perPage lines, each built by newPage.
+func paginateLines(lines []string, perPage int, newPage func(description string) *discordgo.MessageEmbed) []*discordgo.MessageEmbed {
+	pages := make([]*discordgo.MessageEmbed, 0, len(lines)/perPage+1)
+
+	for start := 0; start < len(lines); start += perPage {
+		end := start + perPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		pages = append(pages, newPage(joinLinesWithinLimit(lines[start:end], embedDescriptionLimit)))
+	}
+
+	return pages
+}
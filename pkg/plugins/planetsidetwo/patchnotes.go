@@ -0,0 +1,174 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	embedFieldLimit = 25
+	// patchNoteEmbedLimit keeps each embed under Discord's 6000 character total with room for the title.
+	patchNoteEmbedLimit = 5500
+)
+
+func (p *planetsidetwoPlugin) runPatchNotesCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Patch notes can only be configured in a server.")
+		p.RUnlock()
+		return
+	}
+
+	if strings.ToLower(args["action"]) == "unsubscribe" {
+		removed, err := p.repository.removePatchNoteSubscription(message.Channel())
+
+		p.RLock()
+
+		if err != nil {
+			client.SendMessage(message.Channel(), "Failed to unsubscribe from patch notes.")
+		} else if !removed {
+			client.SendMessage(message.Channel(), "This channel is not subscribed to patch notes.")
+		} else {
+			client.SendMessage(message.Channel(), "Unsubscribed from patch notes!")
+		}
+
+		p.RUnlock()
+		return
+	}
+
+	userID := message.UserID()
+	err = p.repository.addPatchNoteSubscription(&patchNoteSubscription{
+		ChannelID: message.Channel(),
+		GuildID:   channel.GuildID,
+		CreatedBy: &userID,
+	})
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to subscribe to patch notes.")
+	} else {
+		client.SendMessage(message.Channel(), "Subscribed to patch notes! Weapon balance changes will be posted here.")
+	}
+
+	p.RUnlock()
+}
+
+// sendPatchNotes posts the weapon changes found by a catalog sync to every subscribed channel.
+func (p *planetsidetwoPlugin) sendPatchNotes(changes []*weaponChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	session := p.session()
+	if session == nil {
+		log.Printf("Not posting %d weapon changes, the bot is not connected yet", len(changes))
+		return
+	}
+
+	subscriptions, err := p.repository.getPatchNoteSubscriptions()
+	if err != nil {
+		log.Printf("Failed to get patch note subscriptions: %s", err)
+		return
+	}
+
+	embeds := createPatchNoteEmbeds(changes)
+
+	for _, subscription := range subscriptions {
+		color := p.guildEmbedColor(subscription.GuildID, 0)
+
+		for _, embed := range embeds {
+			themed := *embed
+			themed.Color = color
+
+			_, err := session.ChannelMessageSendEmbed(subscription.ChannelID, &themed)
+			if err != nil {
+				log.Printf("Failed to send patch notes to channel '%s': %s", subscription.ChannelID, err)
+				break
+			}
+		}
+	}
+}
+
+// createPatchNoteEmbeds groups the changes by weapon category, one field per category, and
+// spreads the fields over as many embeds as Discord's limits need.
+func createPatchNoteEmbeds(changes []*weaponChange) []*discordgo.MessageEmbed {
+	categories := make(map[string][]*weaponChange)
+	for _, change := range changes {
+		category := change.Weapon.Category
+		if category == "" {
+			category = "Other"
+		}
+		categories[category] = append(categories[category], change)
+	}
+
+	categoryNames := make([]string, 0, len(categories))
+	for category := range categories {
+		categoryNames = append(categoryNames, category)
+	}
+	sort.Strings(categoryNames)
+
+	fields := make([]*discordgo.MessageEmbedField, 0)
+	for _, category := range categoryNames {
+		weaponChanges := categories[category]
+		sort.Slice(weaponChanges, func(i, j int) bool {
+			return weaponChanges[i].Weapon.Name < weaponChanges[j].Weapon.Name
+		})
+
+		blocks := make([]string, len(weaponChanges))
+		for i, change := range weaponChanges {
+			blocks[i] = formatWeaponChange(change)
+		}
+
+		for i, chunk := range chunkLines(blocks, embedFieldValueLimit) {
+			name := category
+			if i > 0 {
+				name += " (continued)"
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:  name,
+				Value: chunk,
+			})
+		}
+	}
+
+	title := fmt.Sprintf("Weapon balance changes (%d weapons)", len(changes))
+
+	embeds := make([]*discordgo.MessageEmbed, 0, 1)
+	var current *discordgo.MessageEmbed
+	size := 0
+
+	for _, field := range fields {
+		fieldSize := len(field.Name) + len(field.Value)
+		if current == nil || len(current.Fields) >= embedFieldLimit || size+fieldSize > patchNoteEmbedLimit {
+			current = &discordgo.MessageEmbed{Title: title}
+			embeds = append(embeds, current)
+			size = len(title)
+		}
+
+		current.Fields = append(current.Fields, field)
+		size += fieldSize
+	}
+
+	return embeds
+}
+
+func formatWeaponChange(change *weaponChange) string {
+	lines := make([]string, 0, len(change.Changes)+1)
+	lines = append(lines, fmt.Sprintf("**%s**", change.Weapon.Name))
+
+	for _, stat := range change.Changes {
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", stat.Stat, stat.OldValue, stat.NewValue))
+	}
+
+	return joinLinesWithinLimit(lines, embedFieldValueLimit)
+}
@@ -1,68 +1,120 @@
 package planetsidetwoplugin
 
 import (
-	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/lampjaw/discordgobot"
-	"golang.org/x/oauth2/clientcredentials"
 )
 
-const CENSUS_IMAGEBASE_URI = "http://census.daybreakgames.com/files/ps2/images/static/"
-const VOIDWELL_URI = "https://voidwell.com/"
+const embedDescriptionLimit = 2048
 
-var voidwellClientConfig clientcredentials.Config
-var voidwellClient *http.Client
+const embedFieldValueLimit = 1024
 
 type planetsidetwoPlugin struct {
 	discordgobot.Plugin
+	dataSource planetsideDataSource
+	selections *selectionManager
+	pagination *paginationManager
+	events     *eventStreams
+	alerts     *alertWatcher
+	feeds      *outfitFeedWatcher
+	activity   *characterActivity
+	voidwell   *voidwellClient
+	config     *serviceConfig
+	client     *discordgobot.DiscordClient
+	settings   guildSettings
+	repository *repository
 }
 
-func New() discordgobot.IPlugin {
-	return &planetsidetwoPlugin{}
+func New(settings guildSettings) *planetsidetwoPlugin {
+	if profilesPath := os.Getenv("PS2TargetProfiles"); profilesPath != "" {
+		if err := loadTargetProfiles(profilesPath); err != nil {
+			log.Printf("Failed to load target profiles from '%s': %s", profilesPath, err)
+		}
+	}
+
+	config := loadServiceConfig()
+	voidwell := newVoidwellClient(config)
+
+	plugin := &planetsidetwoPlugin{
+		dataSource: newDataSource(os.Getenv("PS2DataSource"), os.Getenv("PS2FallbackDataSource"), voidwell),
+		voidwell:   voidwell,
+		config:     config,
+		selections: newSelectionManager(),
+		pagination: newPaginationManager(),
+		events:     newEventStreams(censusServiceID()),
+		alerts:     newAlertWatcher(),
+		feeds:      newOutfitFeedWatcher(),
+		activity:   newCharacterActivity(),
+		settings:   settings,
+		repository: newRepository(),
+	}
+
+	plugin.repository.initRepository()
+
+	go plugin.runSnapshotJob(snapshotInterval())
+	go plugin.runWeaponCatalogJob(weaponCatalogInterval())
+
+	return plugin
+}
+
+// Load is called by the bot once it is connected. It hands the plugin the Discord client used for
+// notifications that are not replies to a command, and restores saved alert and feed subscriptions.
+func (p *planetsidetwoPlugin) Load(client *discordgobot.DiscordClient) error {
+	p.Lock()
+	p.client = client
+	p.Unlock()
+
+	go startMetadataRefresh(planetsideMetadata)
+	go p.startAlerts()
+	go p.startFeeds()
+
+	return nil
+}
+
+// session returns the Discord session used for notifications, or nil before Load.
+func (p *planetsidetwoPlugin) session() *discordgo.Session {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.client == nil || len(p.client.Sessions) == 0 {
+		return nil
+	}
+
+	return p.client.Sessions[0]
 }
 
 func (p *planetsidetwoPlugin) Commands() []*discordgobot.CommandDefinition {
 	return []*discordgobot.CommandDefinition{
 		&discordgobot.CommandDefinition{
 			CommandID: "ps2-character",
-			Triggers: []string{
-				"ps2c",
-				"ps2c-ps4us",
-				"ps2c-ps4eu",
-			},
+			Triggers:  platformTriggers("ps2c"),
 			Arguments: []discordgobot.CommandDefinitionArgument{
 				discordgobot.CommandDefinitionArgument{
-					Pattern: "[a-zA-Z0-9]*",
+					Pattern: "(?:<@!?[0-9]+>|[a-zA-Z0-9]*)",
 					Alias:   "characterName",
 				},
+				platformArgument,
 			},
 			Description: "Get stats for a player.",
 			Callback:    p.runCharacterStatsCommand,
 		},
 		&discordgobot.CommandDefinition{
 			CommandID: "ps2-character-weapons",
-			Triggers: []string{
-				"ps2c",
-				"ps2c-ps4us",
-				"ps2c-ps4eu",
-			},
+			Triggers:  platformTriggers("ps2c"),
 			Arguments: []discordgobot.CommandDefinitionArgument{
 				discordgobot.CommandDefinitionArgument{
 					Pattern: "[a-zA-Z0-9]*",
 					Alias:   "characterName",
 				},
+				// Weapon names never start with '-', so a lone '--platform' flag is left to ps2-character.
 				discordgobot.CommandDefinitionArgument{
-					Pattern: ".*",
+					Pattern: "[^\\s-].*",
 					Alias:   "weaponName",
 				},
 			},
@@ -71,20 +123,34 @@ func (p *planetsidetwoPlugin) Commands() []*discordgobot.CommandDefinition {
 		},
 		&discordgobot.CommandDefinition{
 			CommandID: "ps2-outfit",
-			Triggers: []string{
-				"ps2o",
-				"ps2o-ps4us",
-				"ps2o-ps4eu",
-			},
+			Triggers:  platformTriggers("ps2o"),
 			Arguments: []discordgobot.CommandDefinitionArgument{
 				discordgobot.CommandDefinitionArgument{
 					Pattern: "[a-zA-Z0-9]{1,4}",
 					Alias:   "outfitAlias",
 				},
+				platformArgument,
 			},
 			Description: "Get outfit stats by outfit tag.",
 			Callback:    p.runOutfitStatsCommand,
 		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-character-compare",
+			Triggers:  platformTriggers("ps2compare"),
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]+",
+					Alias:   "firstCharacterName",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]+",
+					Alias:   "secondCharacterName",
+				},
+				platformArgument,
+			},
+			Description: "Compare stats for two players.",
+			Callback:    p.runCharacterCompareCommand,
+		},
 		&discordgobot.CommandDefinition{
 			CommandID: "ps2-weapon",
 			Triggers: []string{
@@ -99,6 +165,256 @@ func (p *planetsidetwoPlugin) Commands() []*discordgobot.CommandDefinition {
 			Description: "Get weapon stats by weapon name.",
 			Callback:    p.runWeaponStatsCommand,
 		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-weapon-find",
+			Triggers: []string{
+				"ps2wfind",
+			},
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  ".*",
+					Alias:    "filters",
+				},
+			},
+			Description: "Search the weapon catalog by stats.",
+			Callback:    p.runWeaponFindCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-weapon-compare",
+			Triggers: []string{
+				"ps2wc",
+			},
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: ".*",
+					Alias:   "weaponNames",
+				},
+			},
+			Description: "Compare two weapons.",
+			Callback:    p.runWeaponCompareCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-weapon-ttk",
+			Triggers: []string{
+				"ps2ttk",
+			},
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: ".*",
+					Alias:   "query",
+				},
+			},
+			Description: "Calculate time to kill for a weapon.",
+			Callback:    p.runTimeToKillCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-progress",
+			Triggers:  platformTriggers("ps2progress"),
+			// The character and period are both optional, so they are told apart after matching.
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  ".*",
+					Alias:    "query",
+				},
+			},
+			Description: "Get a player's progress over the last 7, 30 or 90 days.",
+			Callback:    p.runProgressCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-link",
+			Triggers:  platformTriggers("ps2link"),
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]+",
+					Alias:   "characterName",
+				},
+				platformNameArgument,
+				platformArgument,
+			},
+			Description: "Link your Discord account to a character.",
+			Callback:    p.runLinkCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-unlink",
+			Triggers: []string{
+				"ps2unlink",
+			},
+			Description: "Unlink your character from your Discord account.",
+			Callback:    p.runUnlinkCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-links",
+			Triggers: []string{
+				"ps2links",
+			},
+			ExposureLevel: discordgobot.EXPOSURE_PUBLIC,
+			Description:   "List the characters linked by members of this server.",
+			Callback:      p.runListLinksCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-set-platform",
+			Triggers: []string{
+				"ps2platform",
+			},
+			PermissionLevel: discordgobot.PERMISSION_ADMIN,
+			ExposureLevel:   discordgobot.EXPOSURE_PUBLIC,
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Optional: false,
+					Pattern:  "\\S+",
+					Alias:    "platform",
+				},
+			},
+			Description: "Set the default PlanetSide 2 platform for this server",
+			Callback:    p.runSetPlatformCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-alerts",
+			Triggers: []string{
+				"ps2alerts",
+			},
+			PermissionLevel: discordgobot.PERMISSION_ADMIN,
+			ExposureLevel:   discordgobot.EXPOSURE_PUBLIC,
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "(?i:subscribe|unsubscribe|list)",
+					Alias:   "action",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  "[a-zA-Z]+",
+					Alias:    "world",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  "<@&[0-9]+>",
+					Alias:    "role",
+				},
+			},
+			Description: "Post alert notifications for a server in this channel.",
+			Callback:    p.runAlertsCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-patch-notes",
+			Triggers: []string{
+				"ps2patchnotes",
+			},
+			PermissionLevel: discordgobot.PERMISSION_ADMIN,
+			ExposureLevel:   discordgobot.EXPOSURE_PUBLIC,
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "(?i:subscribe|unsubscribe)",
+					Alias:   "action",
+				},
+			},
+			Description: "Post weapon balance changes in this channel.",
+			Callback:    p.runPatchNotesCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID:       "ps2-feed",
+			Triggers:        platformTriggers("ps2feed"),
+			PermissionLevel: discordgobot.PERMISSION_ADMIN,
+			ExposureLevel:   discordgobot.EXPOSURE_PUBLIC,
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "(?i:outfit|remove)",
+					Alias:   "action",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]{1,4}",
+					Alias:   "outfitAlias",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  "<#[0-9]+>",
+					Alias:    "channel",
+				},
+				platformNameArgument,
+				platformArgument,
+			},
+			Description: "Post an outfit's logins and logouts in a channel.",
+			Callback:    p.runFeedCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-outfit-roster",
+			Triggers:  platformTriggers("ps2roster-view"),
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]{1,4}",
+					Alias:   "outfitAlias",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  ".*",
+					Alias:    "options",
+				},
+			},
+			Description: "List an outfit's members.",
+			Callback:    p.runOutfitRosterCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-online",
+			Triggers:  platformTriggers("ps2online"),
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]{1,4}",
+					Alias:   "outfitAlias",
+				},
+				platformArgument,
+			},
+			Description: "List the outfit members who are online.",
+			Callback:    p.runOnlineCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID: "ps2-population",
+			Triggers:  platformTriggers("ps2pop"),
+			// A '--platform' flag can come with or without a server, so both are read from one argument.
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  ".*",
+					Alias:    "world",
+				},
+			},
+			Description: "Get population and open continents for a server.",
+			Callback:    p.runPopulationCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID:     "ps2-roster",
+			Triggers:      platformTriggers("ps2roster"),
+			ExposureLevel: discordgobot.EXPOSURE_PUBLIC,
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "(?i:add|remove)",
+					Alias:   "action",
+				},
+				discordgobot.CommandDefinitionArgument{
+					Pattern: "[a-zA-Z0-9]+",
+					Alias:   "characterName",
+				},
+				platformNameArgument,
+				platformArgument,
+			},
+			Description: "Add or remove a character from this server's roster.",
+			Callback:    p.runRosterCommand,
+		},
+		&discordgobot.CommandDefinition{
+			CommandID:     "ps2-top",
+			Triggers:      platformTriggers("ps2top"),
+			ExposureLevel: discordgobot.EXPOSURE_PUBLIC,
+			// The stat, platform and options can come in any order, so they are parsed from one argument.
+			Arguments: []discordgobot.CommandDefinitionArgument{
+				discordgobot.CommandDefinitionArgument{
+					Optional: true,
+					Pattern:  ".*",
+					Alias:    "options",
+				},
+			},
+			Description: "Rank this server's roster by a stat.",
+			Callback:    p.runLeaderboardCommand,
+		},
 	}
 }
 
@@ -106,16 +422,36 @@ func (p *planetsidetwoPlugin) Help(bot *discordgobot.Gobot, client *discordgobot
 	commandPrefix := bot.GetCommandPrefix(message)
 
 	return []string{
-		discordgobot.CommandHelp(client, "ps2c", []string{"character name"}, "Get stats for a player.", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2c", []string{"character name|me|@user", "--platform pc|ps4us|ps4eu"}, "Get stats for a player.", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2c-ps4us", []string{"character name"}, "Get stats for a player.", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2c-ps4eu", []string{"character name"}, "Get stats for a player.", commandPrefix),
-		discordgobot.CommandHelp(client, "ps2c", []string{"character name", "weapon name"}, "Get weapon stats for a player.", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2c", []string{"character name", "weapon name", "--platform pc|ps4us|ps4eu"}, "Get weapon stats for a player.", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2c-ps4us", []string{"character name", "weapon name"}, "Get weapon stats for a player.", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2c-ps4eu", []string{"character name", "weapon name"}, "Get weapon stats for a player.", commandPrefix),
-		discordgobot.CommandHelp(client, "ps2o", []string{"outfit name"}, "Get outfit stats", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2compare", []string{"character name", "character name", "--platform pc|ps4us|ps4eu"}, "Compare stats for two players.", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2compare-ps4us", []string{"character name", "character name"}, "Compare stats for two players.", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2compare-ps4eu", []string{"character name", "character name"}, "Compare stats for two players.", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2o", []string{"outfit name", "--platform pc|ps4us|ps4eu"}, "Get outfit stats", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2o-ps4us", []string{"outfit name"}, "Get outfit stats", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2o-ps4eu", []string{"outfit name"}, "Get outfit stats", commandPrefix),
 		discordgobot.CommandHelp(client, "ps2w", []string{"weapon name"}, "Get weapon stats", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2wfind", []string{"category=LMG|\"Assault Rifle\"", "faction=VS", "rpm>700", "mag>=100", "sort=ttk|rpm|mag|damage|velocity|reload|name", "page=n"}, "Search weapons by stats", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2wc", []string{"weapon name | weapon name"}, "Compare two weapons", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2progress", []string{"character name|me|@user", "7d|30d|90d"}, "Get a player's recent progress", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2link", []string{"character name", "pc|ps4us|ps4eu"}, "Link your Discord account to a character", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2unlink", []string{}, "Unlink your character", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2links", []string{}, "List characters linked in this server", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2platform", []string{"pc|ps4us|ps4eu"}, "Set the default platform for this server", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2patchnotes", []string{"subscribe|unsubscribe"}, "Post weapon balance changes in this channel", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2alerts", []string{"subscribe|unsubscribe|list", "server", "@role"}, "Post alert notifications in this channel", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2feed", []string{"outfit|remove", "outfit tag", "#channel", "pc|ps4us|ps4eu"}, "Post outfit logins and logouts in a channel", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2roster-view", []string{"outfit tag", "sort=rank|br|lastonline|name", "--inactive 30d"}, "List an outfit's members", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2online", []string{"outfit tag", "--platform pc|ps4us|ps4eu"}, "List online outfit members, by continent for outfits with a feed", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2pop", []string{"server", "--platform pc|ps4us|ps4eu"}, "Get population and open continents", commandPrefix),
+		discordgobot.CommandHelp(client, "settheme", []string{"house|vs|nc|tr|nso|reset", "#hex|default"}, "Set embed colors for this server", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2roster", []string{"add|remove", "character name", "pc|ps4us|ps4eu"}, "Manage this server's roster", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2top", []string{"kdr|kph|hsr|ivi|score|playtime", "pc|ps4us|ps4eu", "page=n", "minhours=n"}, "Rank this server's roster", commandPrefix),
+		discordgobot.CommandHelp(client, "ps2ttk", []string{"weapon name", "range=m", "target=" + strings.Join(targetProfileNames(), "|"), "headshots=%", "hsmult=x"}, "Calculate time to kill", commandPrefix),
 	}
 }
 
@@ -123,18 +459,34 @@ func (p *planetsidetwoPlugin) Name() string {
 	return "PS2Stats"
 }
 
+// Stats reports the Voidwell response cache counters for the stats command.
+func (p *planetsidetwoPlugin) Stats() map[string]string {
+	hits, misses, staleHits, size := p.voidwell.cache.stats()
+
+	stats := p.events.stats()
+	stats["PS2 cache entries"] = fmt.Sprintf("%d", size)
+	stats["PS2 cache hits"] = fmt.Sprintf("%d (%d stale)", hits, staleHits)
+	stats["PS2 cache misses"] = fmt.Sprintf("%d", misses)
+
+	return stats
+}
+
 func (p *planetsidetwoPlugin) runCharacterStatsCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
-	trigger, args, message := payload.Trigger, payload.Arguments, payload.Message
+	args, message := payload.Arguments, payload.Message
 
-	if trigger == "ps2c-ps4us" {
-		args["platform"] = "ps4us"
-	} else if trigger == "ps2c-ps4eu" {
-		args["platform"] = "ps4eu"
-	} else {
-		args["platform"] = "pc"
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
 	}
 
-	resp, err := voidwellAPIGet(fmt.Sprintf("https://voidwell.com/api/ps2/character/byname/%s?platform=%s", args["characterName"], args["platform"]))
+	p.sendCharacterStats(client, message, args["characterName"], platform)
+}
+
+func (p *planetsidetwoPlugin) sendCharacterStats(client *discordgobot.DiscordClient, message discordgobot.Message, characterReference string, platform string) {
+	character, err := p.lookupCharacter(message, characterReference, platform)
 
 	if err != nil {
 		p.RLock()
@@ -143,8 +495,7 @@ func (p *planetsidetwoPlugin) runCharacterStatsCommand(bot *discordgobot.Gobot,
 		return
 	}
 
-	var character PlanetsideCharacter
-	json.Unmarshal(resp, &character)
+	go p.trackCharacter(character)
 
 	lastSaved, _ := time.Parse(time.RFC3339, character.LastSaved)
 
@@ -226,12 +577,13 @@ func (p *planetsidetwoPlugin) runCharacterStatsCommand(bot *discordgobot.Gobot,
 			Name: character.Name,
 		},
 		Title: "Click here for full stats",
-		URL:   VOIDWELL_URI + "ps2/player/" + character.CharacterId,
-		Color: 0x070707,
+		URL:   p.config.voidwellLink("ps2/player/%s", character.CharacterId),
+		Color: p.embedColor(client, message.Channel(), character.FactionId),
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
-			URL: createCensusImageURI(character.FactionImageId),
+			URL: p.config.censusImage(factionImageID(character.FactionId, character.FactionImageId)),
 		},
 		Fields: fields,
+		Footer: createStaleFooter(character.Stale),
 	}
 
 	p.RLock()
@@ -240,17 +592,28 @@ func (p *planetsidetwoPlugin) runCharacterStatsCommand(bot *discordgobot.Gobot,
 }
 
 func (p *planetsidetwoPlugin) runCharacterWeaponStatsCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
-	trigger, args, message := payload.Trigger, payload.Arguments, payload.Message
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
 
-	if trigger == "ps2c-ps4us" {
-		args["platform"] = "ps4us"
-	} else if trigger == "ps2c-ps4eu" {
-		args["platform"] = "ps4eu"
-	} else {
-		args["platform"] = "pc"
+	// ps2-character already answers a message with no weapon name.
+	if args["weaponName"] == "" {
+		return
 	}
 
-	resp, err := voidwellAPIGet(fmt.Sprintf("https://voidwell.com/api/ps2/character/byname/%s/weapon/%s?platform=%s", args["characterName"], args["weaponName"], args["platform"]))
+	p.resolveWeaponName(client, message, args["weaponName"], func(weaponName string) {
+		p.sendCharacterWeaponStats(client, message, args["characterName"], weaponName, platform)
+	})
+}
+
+func (p *planetsidetwoPlugin) sendCharacterWeaponStats(client *discordgobot.DiscordClient, message discordgobot.Message, characterName string, weaponName string, platform string) {
+	weapon, err := p.dataSource.GetCharacterWeapon(characterName, weaponName, platform)
 
 	if err != nil {
 		p.RLock()
@@ -259,18 +622,15 @@ func (p *planetsidetwoPlugin) runCharacterWeaponStatsCommand(bot *discordgobot.G
 		return
 	}
 
-	var weapon PlanetsideCharacterWeapon
-	json.Unmarshal(resp, &weapon)
-
 	embed := &discordgo.MessageEmbed{
 		Author: &discordgo.MessageEmbedAuthor{
 			Name: weapon.CharacterName + " [" + weapon.WeaponName + "]",
 		},
 		Title: "Click here for full stats",
-		URL:   VOIDWELL_URI + "ps2/player/" + weapon.CharacterId,
-		Color: 0x070707,
+		URL:   p.config.voidwellLink("ps2/player/%s", weapon.CharacterId),
+		Color: p.embedColor(client, message.Channel(), 0),
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
-			URL: createCensusImageURI(weapon.WeaponImageId),
+			URL: p.config.censusImage(weapon.WeaponImageId),
 		},
 		Fields: []*discordgo.MessageEmbedField{
 			&discordgo.MessageEmbedField{
@@ -334,6 +694,7 @@ func (p *planetsidetwoPlugin) runCharacterWeaponStatsCommand(bot *discordgobot.G
 				Inline: true,
 			},
 		},
+		Footer: createStaleFooter(weapon.Stale),
 	}
 
 	p.RLock()
@@ -342,17 +703,17 @@ func (p *planetsidetwoPlugin) runCharacterWeaponStatsCommand(bot *discordgobot.G
 }
 
 func (p *planetsidetwoPlugin) runOutfitStatsCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
-	trigger, args, message := payload.Trigger, payload.Arguments, payload.Message
+	args, message := payload.Arguments, payload.Message
 
-	if trigger == "ps2c-ps4us" {
-		args["platform"] = "ps4us"
-	} else if trigger == "ps2c-ps4eu" {
-		args["platform"] = "ps4eu"
-	} else {
-		args["platform"] = "pc"
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
 	}
 
-	resp, err := voidwellAPIGet(fmt.Sprintf("https://voidwell.com/api/ps2/outfit/byalias/%s?platform=%s", args["outfitAlias"], args["platform"]))
+	outfit, err := p.dataSource.GetOutfit(args["outfitAlias"], platform)
 
 	if err != nil {
 		p.RLock()
@@ -361,18 +722,15 @@ func (p *planetsidetwoPlugin) runOutfitStatsCommand(bot *discordgobot.Gobot, cli
 		return
 	}
 
-	var outfit PlanetsideOutfit
-	json.Unmarshal(resp, &outfit)
-
 	embed := &discordgo.MessageEmbed{
 		Author: &discordgo.MessageEmbedAuthor{
 			Name: "[" + outfit.Alias + "] " + outfit.Name,
 		},
 		Title: "Click here for full stats",
-		URL:   VOIDWELL_URI + "ps2/outfit/" + outfit.OutfitId,
-		Color: 0x070707,
+		URL:   p.config.voidwellLink("ps2/outfit/%s", outfit.OutfitId),
+		Color: p.embedColor(client, message.Channel(), outfit.FactionId),
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
-			URL: createCensusImageURI(outfit.FactionImageId),
+			URL: p.config.censusImage(factionImageID(outfit.FactionId, outfit.FactionImageId)),
 		},
 		Fields: []*discordgo.MessageEmbedField{
 			&discordgo.MessageEmbedField{
@@ -406,6 +764,7 @@ func (p *planetsidetwoPlugin) runOutfitStatsCommand(bot *discordgobot.Gobot, cli
 				Inline: true,
 			},
 		},
+		Footer: createStaleFooter(outfit.Stale),
 	}
 
 	p.RLock()
@@ -416,7 +775,13 @@ func (p *planetsidetwoPlugin) runOutfitStatsCommand(bot *discordgobot.Gobot, cli
 func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
 	args, message := payload.Arguments, payload.Message
 
-	resp, err := voidwellAPIGet(fmt.Sprintf("https://voidwell.com/api/ps2/weaponinfo/byname/%s", args["weaponName"]))
+	p.resolveWeaponName(client, message, args["weaponName"], func(weaponName string) {
+		p.sendWeaponStats(client, message, weaponName)
+	})
+}
+
+func (p *planetsidetwoPlugin) sendWeaponStats(client *discordgobot.DiscordClient, message discordgobot.Message, weaponName string) {
+	weapon, err := p.getWeapon(weaponName)
 
 	if err != nil {
 		p.RLock()
@@ -425,9 +790,6 @@ func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, cli
 		return
 	}
 
-	var weapon PlanetsideWeapon
-	json.Unmarshal(resp, &weapon)
-
 	fields := make([]*discordgo.MessageEmbedField, 0)
 
 	factionRestriction := "None"
@@ -513,7 +875,7 @@ func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, cli
 		fields = append(fields,
 			&discordgo.MessageEmbedField{
 				Name:   "Damage",
-				Value:  fmt.Sprintf("%d / %dm / %d / %dm", weapon.MaxDamage, weapon.MaxDamageRange, weapon.MinDamage, weapon.MinDamageRange),
+				Value:  formatDamageFalloff(weapon.MaxDamage, float32(weapon.MaxDamageRange), weapon.MinDamage, float32(weapon.MinDamageRange)),
 				Inline: false,
 			},
 		)
@@ -523,7 +885,7 @@ func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, cli
 		fields = append(fields,
 			&discordgo.MessageEmbedField{
 				Name:   "Indirect damage",
-				Value:  fmt.Sprintf("%d / %0.fm / %d / %0.fm", weapon.IndirectMaxDamage, weapon.IndirectMaxDamageRange, weapon.IndirectMinDamage, weapon.IndirectMinDamageRange),
+				Value:  formatDamageFalloff(weapon.IndirectMaxDamage, weapon.IndirectMaxDamageRange, weapon.IndirectMinDamage, weapon.IndirectMinDamageRange),
 				Inline: false,
 			},
 		)
@@ -533,7 +895,7 @@ func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, cli
 		fields = append(fields,
 			&discordgo.MessageEmbedField{
 				Name:   "Hip accuracy",
-				Value:  fmt.Sprintf("%0.2f / %0.2f / %0.2f / %0.2f / %0.2f", weapon.HipAcc.Crouching, weapon.HipAcc.CrouchWalking, weapon.HipAcc.Standing, weapon.HipAcc.Running, weapon.HipAcc.Cof),
+				Value:  formatAccuracyState(weapon.HipAcc),
 				Inline: false,
 			},
 		)
@@ -543,7 +905,7 @@ func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, cli
 		fields = append(fields,
 			&discordgo.MessageEmbedField{
 				Name:   "Aim accuracy",
-				Value:  fmt.Sprintf("%0.2f / %0.2f / %0.2f / %0.2f / %0.2f", weapon.AimAcc.Crouching, weapon.AimAcc.CrouchWalking, weapon.AimAcc.Standing, weapon.AimAcc.Running, weapon.AimAcc.Cof),
+				Value:  formatAccuracyState(weapon.AimAcc),
 				Inline: false,
 			},
 		)
@@ -553,78 +915,78 @@ func (p *planetsidetwoPlugin) runWeaponStatsCommand(bot *discordgobot.Gobot, cli
 		Author: &discordgo.MessageEmbedAuthor{
 			Name: weapon.Name,
 		},
-		Title: "Click here for full stats",
-		URL:   fmt.Sprintf("%sps2/item/%d", VOIDWELL_URI, weapon.ItemID),
-		Color: 0x070707,
-		Thumbnail: &discordgo.MessageEmbedThumbnail{
-			URL: createCensusImageURI(weapon.ImageID),
-		},
+		Color:       p.embedColor(client, message.Channel(), weapon.FactionID),
 		Description: weapon.Description,
 		Fields:      fields,
+		Footer:      createStaleFooter(weapon.Stale),
 	}
 
-	p.RLock()
-	client.SendEmbedMessage(message.Channel(), embed)
-	p.RUnlock()
-}
-
-func createCensusImageURI(imageID int) string {
-	return CENSUS_IMAGEBASE_URI + fmt.Sprintf("%v", imageID) + ".png"
-}
-
-func insertSlice(arr []*discordgo.MessageEmbedField, value *discordgo.MessageEmbedField, index int) []*discordgo.MessageEmbedField {
-	return append(arr[:index], append([]*discordgo.MessageEmbedField{value}, arr[index:]...)...)
-}
+	// Not every weapon has an item page or image to link to.
+	if weapon.ItemID > 0 {
+		embed.Title = "Click here for full stats"
+		embed.URL = p.config.voidwellLink("ps2/item/%d", weapon.ItemID)
+	}
 
-func voidwellAPIGet(uri string) (json.RawMessage, error) {
-	if voidwellClient == nil {
-		voidwellClientConfig := clientcredentials.Config{
-			ClientID:     os.Getenv("VoidwellClientId"),
-			ClientSecret: os.Getenv("VoidwellClientSecret"),
-			TokenURL:     "https://auth.voidwell.com/connect/token",
-			Scopes:       []string{"voidwell-daybreakgames", "voidwell-api"},
+	if weapon.ImageID > 0 {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: p.config.censusImage(weapon.ImageID),
 		}
-
-		ctx := context.Background()
-		voidwellClient = voidwellClientConfig.Client(ctx)
 	}
 
-	resp, err := voidwellClient.Get(uri)
-
+	chart, legend, err := renderDamageChart([]*PlanetsideWeapon{weapon})
 	if err != nil {
-		log.Println(err)
-		return nil, err
+		log.Printf("Failed to render damage chart for '%s': %s", weapon.Name, err)
 	}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	if chart != nil && len(legend) > 1 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Chart",
+			Value: strings.Join(legend, "\n"),
+		})
+	}
 
-	if resp.StatusCode != 200 {
-		return nil, errors.New(string(body))
+	p.sendEmbedWithChart(client, message.Channel(), embed, chart)
+}
+
+// formatDamageFalloff describes damage dropping off between two ranges, e.g. "143 up to 10m, falling to 125 at 65m".
+func formatDamageFalloff(maxDamage int, maxDamageRange float32, minDamage int, minDamageRange float32) string {
+	if maxDamage == minDamage {
+		return fmt.Sprintf("%d at all ranges", maxDamage)
 	}
 
-	var jsonResponse json.RawMessage
-	err = json.Unmarshal(body, &jsonResponse)
+	return fmt.Sprintf("%d up to %0.fm, falling to %d at %0.fm", maxDamage, maxDamageRange, minDamage, minDamageRange)
+}
 
-	if err != nil {
-		log.Println(fmt.Sprintf("Failed to unmarshal for %v: %v", uri, err))
-		return nil, err
+// createStaleFooter returns a footer warning that the embed was built from an expired cache entry.
+func createStaleFooter(stale bool) *discordgo.MessageEmbedFooter {
+	if !stale {
+		return nil
 	}
 
-	return jsonResponse, nil
+	return &discordgo.MessageEmbedFooter{
+		Text: "Voidwell is unavailable, showing cached data",
+	}
 }
 
-func getFactionName(factionID int) string {
-	switch factionID {
-	case 1:
-		return "Vanu Sovereignty"
-	case 2:
-		return "New Conglomerate"
-	case 3:
-		return "Terran Republic"
-	case 4:
-		return "NS Operatives"
+// joinLinesWithinLimit joins lines with newlines, dropping trailing lines that do not fit within limit characters.
+func joinLinesWithinLimit(lines []string, limit int) string {
+	result := ""
+
+	for i, line := range lines {
+		more := fmt.Sprintf("\n…and %d more", len(lines)-i)
+		if len(result)+len(line)+1+len(more) > limit {
+			return result + more
+		}
+
+		if i > 0 {
+			result += "\n"
+		}
+		result += line
 	}
 
-	return "Unknown"
+	return result
+}
+
+func insertSlice(arr []*discordgo.MessageEmbedField, value *discordgo.MessageEmbedField, index int) []*discordgo.MessageEmbedField {
+	return append(arr[:index], append([]*discordgo.MessageEmbedField{value}, arr[index:]...)...)
 }
@@ -0,0 +1,114 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+// matchArguments parses text the way discordgobot matches a command definition's arguments,
+// returning false when the definition would not be dispatched.
+func matchArguments(definition *discordgobot.CommandDefinition, text string) (map[string]string, bool) {
+	args := make(map[string]string)
+	if len(definition.Arguments) == 0 {
+		return args, true
+	}
+
+	patterns := make([]string, len(definition.Arguments))
+	for i, argument := range definition.Arguments {
+		if i == 0 {
+			patterns[i] = fmt.Sprintf("(?P<%s>%s)", argument.Alias, argument.Pattern)
+		} else {
+			patterns[i] = fmt.Sprintf("(?:\\s+(?P<%s>%s))", argument.Alias, argument.Pattern)
+		}
+
+		if argument.Optional {
+			patterns[i] += "?"
+		}
+	}
+
+	pattern := regexp.MustCompile("^" + strings.Join(patterns, "") + "$")
+	match := pattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return nil, false
+	}
+
+	for i, name := range pattern.SubexpNames()[1:] {
+		args[name] = match[i+1]
+	}
+
+	return args, true
+}
+
+// dispatchedCommands returns the IDs of every command the trigger and argument text would run.
+func dispatchedCommands(trigger string, text string) ([]string, map[string]map[string]string) {
+	commandIDs := make([]string, 0)
+	arguments := make(map[string]map[string]string)
+
+	for _, definition := range (&planetsidetwoPlugin{}).Commands() {
+		triggered := false
+		for _, t := range definition.Triggers {
+			triggered = triggered || t == trigger
+		}
+
+		if !triggered {
+			continue
+		}
+
+		if args, ok := matchArguments(definition, text); ok {
+			commandIDs = append(commandIDs, definition.CommandID)
+			arguments[definition.CommandID] = args
+		}
+	}
+
+	sort.Strings(commandIDs)
+
+	return commandIDs, arguments
+}
+
+func TestCommandDispatch(t *testing.T) {
+	tests := []struct {
+		trigger    string
+		text       string
+		commandIDs []string
+		arguments  map[string]string
+	}{
+		{"ps2c", "Foo", []string{"ps2-character"}, nil},
+		{"ps2c", "Foo --platform ps4eu", []string{"ps2-character"}, nil},
+		{"ps2c", "Foo --platform=ps4eu", []string{"ps2-character"}, nil},
+		{"ps2c", "Foo Gauss SAW", []string{"ps2-character-weapons"}, map[string]string{"weaponName": "Gauss SAW"}},
+		{"ps2c", "Foo Gauss SAW --platform ps4eu", []string{"ps2-character-weapons"}, map[string]string{"weaponName": "Gauss SAW --platform ps4eu"}},
+		{"ps2pop", "", []string{"ps2-population"}, map[string]string{"world": ""}},
+		{"ps2pop", "Emerald", []string{"ps2-population"}, map[string]string{"world": "Emerald"}},
+		{"ps2pop", "--platform ps4eu", []string{"ps2-population"}, map[string]string{"world": "--platform ps4eu"}},
+		{"ps2pop", "Ceres --platform ps4eu", []string{"ps2-population"}, map[string]string{"world": "Ceres --platform ps4eu"}},
+		{"ps2progress", "", []string{"ps2-progress"}, map[string]string{"query": ""}},
+		{"ps2progress", "30d", []string{"ps2-progress"}, map[string]string{"query": "30d"}},
+		{"ps2progress", "<@1234> 90d --platform ps4eu", []string{"ps2-progress"}, map[string]string{"query": "<@1234> 90d --platform ps4eu"}},
+		{"ps2top", "", []string{"ps2-top"}, map[string]string{"options": ""}},
+		{"ps2top", "kph", []string{"ps2-top"}, map[string]string{"options": "kph"}},
+		{"ps2top", "ps4eu", []string{"ps2-top"}, map[string]string{"options": "ps4eu"}},
+		{"ps2top", "page=2", []string{"ps2-top"}, map[string]string{"options": "page=2"}},
+		{"ps2top", "minhours=10", []string{"ps2-top"}, map[string]string{"options": "minhours=10"}},
+		{"ps2top", "kph ps4eu page=2 minhours=10", []string{"ps2-top"}, map[string]string{"options": "kph ps4eu page=2 minhours=10"}},
+	}
+
+	for _, test := range tests {
+		commandIDs, arguments := dispatchedCommands(test.trigger, test.text)
+
+		if strings.Join(commandIDs, ",") != strings.Join(test.commandIDs, ",") {
+			t.Errorf("%s %s dispatched %v, want %v", test.trigger, test.text, commandIDs, test.commandIDs)
+			continue
+		}
+
+		for alias, expected := range test.arguments {
+			if actual := arguments[commandIDs[0]][alias]; actual != expected {
+				t.Errorf("%s %s parsed %s %q, want %q", test.trigger, test.text, alias, actual, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	platformPC    = "pc"
+	platformPS4US = "ps4us"
+	platformPS4EU = "ps4eu"
+)
+
+// platformAliases maps the spellings accepted from users to platform identifiers.
+var platformAliases = map[string]string{
+	"pc":     platformPC,
+	"ps4us":  platformPS4US,
+	"ps4-us": platformPS4US,
+	"ps4eu":  platformPS4EU,
+	"ps4-eu": platformPS4EU,
+}
+
+var platformDisplayNames = map[string]string{
+	platformPC:    "PC",
+	platformPS4US: "PS4 US",
+	platformPS4EU: "PS4 EU",
+}
+
+// platformTriggerSuffixes are appended to a command trigger to select a platform, e.g. 'ps2c-ps4eu'.
+var platformTriggerSuffixes = []string{platformPS4US, platformPS4EU}
+
+var platformFlagPattern = regexp.MustCompile(`(?i)(?:^|\s)--platform[= ](\S+)`)
+
+// platformArgument is the optional trailing '--platform' argument for commands whose other arguments are strict patterns.
+var platformArgument = discordgobot.CommandDefinitionArgument{
+	Optional: true,
+	Pattern:  "--platform[= ]\\S+",
+	Alias:    "platformFlag",
+}
+
+// platformNameArgument is an optional positional platform such as 'ps4eu'.
+var platformNameArgument = discordgobot.CommandDefinitionArgument{
+	Optional: true,
+	Pattern:  "(?i:pc|ps4-?us|ps4-?eu)",
+	Alias:    "platform",
+}
+
+// guildSettings gives access to the per-guild settings stored in the guild profile.
+type guildSettings interface {
+	GetGuildPS2Platform(guildID string) (*string, error)
+	SetGuildPS2Platform(guildID string, userID string, platform string) error
+	GetGuildTheme(guildID string) (map[string]int, error)
+}
+
+// platformTriggers returns the trigger along with its platform suffixed aliases.
+func platformTriggers(trigger string) []string {
+	triggers := []string{trigger}
+	for _, suffix := range platformTriggerSuffixes {
+		triggers = append(triggers, trigger+"-"+suffix)
+	}
+
+	return triggers
+}
+
+func parsePlatform(value string) (string, bool) {
+	platform, ok := platformAliases[strings.ToLower(value)]
+	return platform, ok
+}
+
+func platformDisplayName(platform string) string {
+	if name, ok := platformDisplayNames[platform]; ok {
+		return name
+	}
+
+	return strings.ToUpper(platform)
+}
+
+// resolvePlatform determines the platform a command should query. A '--platform' flag in any
+// argument or a positional 'platform' argument wins over a trigger suffix, which wins over the
+// guild's default platform. The flag is removed from the arguments it was found in.
+func (p *planetsidetwoPlugin) resolvePlatform(client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) (string, error) {
+	requested := payload.Arguments["platform"]
+	for alias, value := range payload.Arguments {
+		if match := platformFlagPattern.FindStringSubmatch(value); match != nil {
+			requested = match[1]
+			payload.Arguments[alias] = strings.TrimSpace(platformFlagPattern.ReplaceAllString(value, " "))
+		}
+	}
+
+	if requested != "" {
+		platform, ok := parsePlatform(requested)
+		if !ok {
+			return "", fmt.Errorf("Unknown platform '%s'. Use pc, ps4us or ps4eu.", requested)
+		}
+		return platform, nil
+	}
+
+	if i := strings.LastIndex(payload.Trigger, "-"); i >= 0 {
+		if platform, ok := parsePlatform(payload.Trigger[i+1:]); ok {
+			return platform, nil
+		}
+	}
+
+	return p.guildDefaultPlatform(client, payload.Message), nil
+}
+
+func (p *planetsidetwoPlugin) guildDefaultPlatform(client *discordgobot.DiscordClient, message discordgobot.Message) string {
+	if p.settings == nil {
+		return platformPC
+	}
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		return platformPC
+	}
+
+	platform, err := p.settings.GetGuildPS2Platform(channel.GuildID)
+	if err != nil {
+		log.Printf("Failed to get default platform for guild '%s': %s", channel.GuildID, err)
+		return platformPC
+	}
+
+	if platform == nil || *platform == "" {
+		return platformPC
+	}
+
+	return *platform
+}
+
+func (p *planetsidetwoPlugin) runSetPlatformCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, ok := parsePlatform(args["platform"])
+	if !ok {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("Unknown platform '%s'. Use pc, ps4us or ps4eu.", args["platform"]))
+		p.RUnlock()
+		return
+	}
+
+	channel, err := client.Channel(message.Channel())
+	if err == nil {
+		err = p.settings.SetGuildPS2Platform(channel.GuildID, message.UserID(), platform)
+	}
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to set default platform.")
+	} else {
+		client.SendMessage(message.Channel(), fmt.Sprintf("Default platform set to %s!", platformDisplayName(platform)))
+	}
+
+	p.RUnlock()
+}
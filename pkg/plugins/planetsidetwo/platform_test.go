@@ -0,0 +1,89 @@
+package planetsidetwoplugin
+
+import (
+	"testing"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		ok       bool
+	}{
+		{"pc", platformPC, true},
+		{"PC", platformPC, true},
+		{"ps4us", platformPS4US, true},
+		{"PS4-US", platformPS4US, true},
+		{"ps4eu", platformPS4EU, true},
+		{"ps4-eu", platformPS4EU, true},
+		{"xbox", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		actual, ok := parsePlatform(test.value)
+		if actual != test.expected || ok != test.ok {
+			t.Errorf("parsePlatform(%q) = %q, %t, want %q, %t", test.value, actual, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestResolvePlatform(t *testing.T) {
+	tests := []struct {
+		name      string
+		trigger   string
+		arguments map[string]string
+		expected  string
+		remaining map[string]string
+		err       string
+	}{
+		{"guild default without settings", "ps2c", map[string]string{"characterName": "Foo"}, platformPC, nil, ""},
+		{"trigger suffix", "ps2c-ps4eu", map[string]string{"characterName": "Foo"}, platformPS4EU, nil, ""},
+		{"trigger without platform suffix", "ps2-top", map[string]string{"options": ""}, platformPC, nil, ""},
+		{"positional argument", "ps2pop", map[string]string{"platform": "ps4us"}, platformPS4US, nil, ""},
+		{"positional argument over trigger", "ps2pop-ps4eu", map[string]string{"platform": "pc"}, platformPC, nil, ""},
+		{
+			"flag over trigger",
+			"ps2c-ps4us", map[string]string{"characterName": "Foo", "platformFlag": "--platform ps4eu"},
+			platformPS4EU, map[string]string{"characterName": "Foo", "platformFlag": ""}, "",
+		},
+		{
+			"flag over positional argument",
+			"ps2pop", map[string]string{"platform": "pc", "world": "Ceres --platform=ps4eu"},
+			platformPS4EU, map[string]string{"world": "Ceres"}, "",
+		},
+		{
+			"flag inside free text",
+			"ps2c", map[string]string{"characterName": "Foo", "weaponName": "Gauss SAW --platform PS4-US"},
+			platformPS4US, map[string]string{"weaponName": "Gauss SAW"}, "",
+		},
+		{"unknown flag", "ps2c", map[string]string{"platformFlag": "--platform xbox"}, "", nil, "Unknown platform 'xbox'. Use pc, ps4us or ps4eu."},
+		{"unknown positional argument", "ps2pop", map[string]string{"platform": "xbox"}, "", nil, "Unknown platform 'xbox'. Use pc, ps4us or ps4eu."},
+	}
+
+	plugin := &planetsidetwoPlugin{}
+
+	for _, test := range tests {
+		payload := discordgobot.CommandPayload{Trigger: test.trigger, Arguments: test.arguments}
+
+		platform, err := plugin.resolvePlatform(nil, payload)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: resolvePlatform returned error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil || platform != test.expected {
+			t.Errorf("%s: resolvePlatform = %q, %v, want %q", test.name, platform, err, test.expected)
+		}
+
+		for alias, expected := range test.remaining {
+			if actual := payload.Arguments[alias]; actual != expected {
+				t.Errorf("%s: left %s %q, want %q", test.name, alias, actual, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+func (p *planetsidetwoPlugin) runPopulationCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	worldID := ""
+	if args["world"] != "" {
+		var ok bool
+		worldID, ok = findWorld(args["world"])
+		if !ok {
+			p.RLock()
+			client.SendMessage(message.Channel(), fmt.Sprintf("Unknown server '%s'.", args["world"]))
+			p.RUnlock()
+			return
+		}
+		platform = worldPlatform(worldID)
+	}
+
+	worlds, err := p.dataSource.GetWorldStates(platform)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	var embed *discordgo.MessageEmbed
+
+	if worldID != "" {
+		var world *PlanetsideWorldState
+		for _, w := range worlds {
+			if w.WorldID == worldID {
+				world = w
+			}
+		}
+
+		if world == nil {
+			p.RLock()
+			client.SendMessage(message.Channel(), fmt.Sprintf("No status available for %s.", worldName(worldID)))
+			p.RUnlock()
+			return
+		}
+
+		embed = createWorldStateEmbed(world)
+		embed.Color = p.embedColor(client, message.Channel(), dominantFaction(world.Population))
+	} else {
+		embed = createWorldStatesEmbed(worlds, platform)
+		embed.Color = p.embedColor(client, message.Channel(), dominantFaction(sumPopulation(worlds)))
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
+
+func createWorldStateEmbed(world *PlanetsideWorldState) *discordgo.MessageEmbed {
+	fields := []*discordgo.MessageEmbedField{
+		&discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("Population (%d)", totalPopulation(world)),
+			Value: formatPopulation(world),
+		},
+	}
+
+	for _, continent := range sortedContinents(world) {
+		if !continent.IsOpen {
+			continue
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   continent.Name,
+			Value:  formatTerritory(continent),
+			Inline: true,
+		})
+	}
+
+	locked := lockedContinentNames(world)
+	if len(locked) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Locked",
+			Value: strings.Join(locked, ", "),
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:  worldName(world.WorldID) + " status",
+		Fields: fields,
+		Footer: createStaleFooter(world.Stale),
+	}
+}
+
+func createWorldStatesEmbed(worlds []*PlanetsideWorldState, platform string) *discordgo.MessageEmbed {
+	fields := make([]*discordgo.MessageEmbedField, 0, len(worlds))
+	stale := false
+
+	for _, world := range worlds {
+		open := make([]string, 0)
+		for _, continent := range sortedContinents(world) {
+			if continent.IsOpen {
+				open = append(open, continent.Name)
+			}
+		}
+
+		if len(open) == 0 {
+			open = append(open, "None")
+		}
+
+		stale = stale || world.Stale
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s (%d)", formatWorldName(world.WorldID), totalPopulation(world)),
+			Value: fmt.Sprintf("%s\nOpen: %s", formatPopulationSummary(world), strings.Join(open, ", ")),
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:  platformDisplayName(platform) + " server status",
+		Fields: fields,
+		Footer: createStaleFooter(stale),
+	}
+}
+
+func formatPopulation(world *PlanetsideWorldState) string {
+	total := totalPopulation(world)
+	if total == 0 {
+		return "Population unavailable"
+	}
+
+	factionIDs := planetsideMetadata.factionIDs()
+	lines := make([]string, 0, len(factionIDs))
+	for _, factionID := range factionIDs {
+		count := world.Population[factionID]
+		lines = append(lines, fmt.Sprintf("%s: %d (%0.1f%%)", getFactionName(factionID), count, float64(count)/float64(total)*100))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func formatPopulationSummary(world *PlanetsideWorldState) string {
+	if totalPopulation(world) == 0 {
+		return "Population unavailable"
+	}
+
+	factionIDs := planetsideMetadata.factionIDs()
+	parts := make([]string, 0, len(factionIDs))
+	for _, factionID := range factionIDs {
+		parts = append(parts, fmt.Sprintf("%s %d", getFactionCode(factionID), world.Population[factionID]))
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+func formatTerritory(continent *PlanetsideContinentState) string {
+	lines := make([]string, 0, len(continent.Territory))
+	for _, factionID := range planetsideMetadata.factionIDs() {
+		if share, ok := continent.Territory[factionID]; ok {
+			lines = append(lines, fmt.Sprintf("%s: %0.1f%%", getFactionName(factionID), share))
+		}
+	}
+
+	if len(lines) == 0 {
+		return "Territory unavailable"
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func lockedContinentNames(world *PlanetsideWorldState) []string {
+	names := make([]string, 0)
+	for _, continent := range sortedContinents(world) {
+		if !continent.IsOpen {
+			names = append(names, continent.Name)
+		}
+	}
+
+	return names
+}
+
+func sortedContinents(world *PlanetsideWorldState) []*PlanetsideContinentState {
+	continents := make([]*PlanetsideContinentState, len(world.Continents))
+	copy(continents, world.Continents)
+
+	sort.SliceStable(continents, func(i, j int) bool {
+		return continents[i].Name < continents[j].Name
+	})
+
+	return continents
+}
+
+func totalPopulation(world *PlanetsideWorldState) int {
+	total := 0
+	for _, count := range world.Population {
+		total += count
+	}
+
+	return total
+}
+
+func sumPopulation(worlds []*PlanetsideWorldState) map[int]int {
+	population := make(map[int]int)
+	for _, world := range worlds {
+		for factionID, count := range world.Population {
+			population[factionID] += count
+		}
+	}
+
+	return population
+}
+
+// dominantFaction returns the faction with the highest count, or 0 when nothing was counted.
+func dominantFaction(counts map[int]int) int {
+	dominant, highest := 0, 0
+	for _, factionID := range planetsideMetadata.factionIDs() {
+		if counts[factionID] > highest {
+			dominant, highest = factionID, counts[factionID]
+		}
+	}
+
+	return dominant
+}
+
+// formatWorldName names the world along with its region, e.g. 'Emerald (US East)'.
+func formatWorldName(worldID string) string {
+	if region := worldRegion(worldID); region != "" {
+		return fmt.Sprintf("%s (%s)", worldName(worldID), region)
+	}
+
+	return worldName(worldID)
+}
@@ -0,0 +1,291 @@
+package planetsidetwoplugin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	defaultSnapshotInterval = 24 * time.Hour
+	// snapshotRequestDelay spaces out lookups during a snapshot run.
+	snapshotRequestDelay = 2 * time.Second
+	// trackedCharacterIdleLimit is how long a character nobody requests keeps being snapshotted.
+	// Linked and roster characters are snapshotted for as long as they stay linked or listed.
+	trackedCharacterIdleLimit = 30 * 24 * time.Hour
+)
+
+var progressWindows = map[string]time.Duration{
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"90d": 90 * 24 * time.Hour,
+}
+
+var progressWindowPattern = regexp.MustCompile(`(?i)^[0-9]+d$`)
+
+// parseProgressQuery splits the period off the character reference. Anything shaped like a period
+// is read as one, so 'ps2progress 30d' shows your own progress rather than a character named '30d'.
+func parseProgressQuery(text string) (string, string, error) {
+	characterName, windowName := "", "7d"
+
+	for _, token := range strings.Fields(text) {
+		if progressWindowPattern.MatchString(token) {
+			windowName = strings.ToLower(token)
+			continue
+		}
+
+		if characterName != "" {
+			return "", "", errors.New("Please provide a single character name.")
+		}
+		characterName = token
+	}
+
+	return characterName, windowName, nil
+}
+
+func snapshotInterval() time.Duration {
+	if value := os.Getenv("PS2SnapshotInterval"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Invalid PS2SnapshotInterval '%s', using %s", value, defaultSnapshotInterval)
+	}
+
+	return defaultSnapshotInterval
+}
+
+func newCharacterSnapshot(character *PlanetsideCharacter) *characterSnapshot {
+	return &characterSnapshot{
+		CharacterID:  character.CharacterId,
+		Platform:     character.Platform,
+		SnapshotDate: time.Now().UTC(),
+		Kills:        character.Kills,
+		Deaths:       character.Deaths,
+		PlayTime:     character.PlayTime,
+		Score:        character.Score,
+		BattleRank:   character.BattleRank,
+		Prestige:     character.Prestige,
+		KillsPerHour: character.KillsPerHour,
+	}
+}
+
+// trackCharacter adds the character to the snapshot schedule, or keeps it there for another
+// trackedCharacterIdleLimit, taking its first snapshot right away so that history starts
+// building from the first lookup.
+func (p *planetsidetwoPlugin) trackCharacter(character *PlanetsideCharacter) {
+	if character.CharacterId == "" || character.Stale {
+		return
+	}
+
+	isNew, err := p.repository.trackCharacter(character.CharacterId, character.Platform, character.Name)
+	if err != nil {
+		log.Printf("Failed to track character '%s': %s", character.CharacterId, err)
+		return
+	}
+
+	if !isNew {
+		return
+	}
+
+	err = p.repository.insertCharacterSnapshot(newCharacterSnapshot(character))
+	if err != nil {
+		log.Printf("Failed to snapshot character '%s': %s", character.CharacterId, err)
+	}
+}
+
+// runSnapshotJob snapshots every tracked character on each interval tick.
+func (p *planetsidetwoPlugin) runSnapshotJob(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.snapshotTrackedCharacters(interval)
+
+	for range ticker.C {
+		p.snapshotTrackedCharacters(interval)
+	}
+}
+
+func (p *planetsidetwoPlugin) snapshotTrackedCharacters(interval time.Duration) {
+	untracked, err := p.repository.untrackIdleCharacters(time.Now().UTC().Add(-trackedCharacterIdleLimit))
+	if err != nil {
+		log.Printf("Failed to untrack idle characters: %s", err)
+	} else if untracked > 0 {
+		log.Printf("Stopped tracking %d characters nobody requested in %d days", untracked, int(trackedCharacterIdleLimit.Hours()/24))
+	}
+
+	characters, err := p.repository.getTrackedCharacters()
+	if err != nil {
+		log.Printf("Failed to get tracked characters: %s", err)
+		return
+	}
+
+	snapshotted, stale := 0, 0
+
+	for _, tracked := range characters {
+		// Restarts should not produce a burst of snapshots for characters captured recently.
+		latest, err := p.repository.getLatestSnapshot(tracked.CharacterID, tracked.Platform)
+		if err != nil {
+			log.Printf("Failed to get latest snapshot for '%s': %s", tracked.CharacterID, err)
+			continue
+		}
+
+		if latest != nil && time.Since(latest.SnapshotDate) < interval/2 {
+			continue
+		}
+
+		time.Sleep(snapshotRequestDelay)
+
+		character, err := p.dataSource.GetCharacterByID(tracked.CharacterID, tracked.Platform)
+		if err != nil {
+			log.Printf("Failed to get character '%s' to snapshot: %s", tracked.CharacterID, err)
+			continue
+		}
+
+		// A stale response would record old stats as new, so skip the character until next time.
+		if character.Stale {
+			log.Printf("Skipped snapshot of character '%s' since only stale stats are available", tracked.CharacterID)
+			stale++
+			continue
+		}
+
+		err = p.repository.insertCharacterSnapshot(newCharacterSnapshot(character))
+		if err != nil {
+			log.Printf("Failed to snapshot character '%s': %s", tracked.CharacterID, err)
+			continue
+		}
+
+		snapshotted++
+	}
+
+	log.Printf("Snapshotted %d of %d tracked characters, skipped %d with stale stats", snapshotted, len(characters), stale)
+}
+
+func (p *planetsidetwoPlugin) runProgressCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	characterName, windowName, err := parseProgressQuery(args["query"])
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	window, ok := progressWindows[windowName]
+	if !ok {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("Unknown window '%s'. Use 7d, 30d or 90d.", windowName))
+		p.RUnlock()
+		return
+	}
+
+	character, err := p.lookupCharacter(message, characterName, platform)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	p.trackCharacter(character)
+
+	baseline, err := p.repository.getEarliestSnapshotSince(character.CharacterId, character.Platform, time.Now().UTC().Add(-window))
+	if err != nil {
+		log.Printf("Failed to get snapshot for '%s': %s", character.CharacterId, err)
+		p.RLock()
+		client.SendMessage(message.Channel(), "Failed to get character history.")
+		p.RUnlock()
+		return
+	}
+
+	if baseline == nil || character.PlayTime == baseline.PlayTime {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("No progress recorded for %s in the last %s yet. History is recorded from the first lookup, check back later!", character.Name, windowName))
+		p.RUnlock()
+		return
+	}
+
+	kills := character.Kills - baseline.Kills
+	deaths := character.Deaths - baseline.Deaths
+	playTimeHours := float32(character.PlayTime-baseline.PlayTime) / 3600.0
+
+	kdr := float32(kills)
+	if deaths > 0 {
+		kdr = float32(kills) / float32(deaths)
+	}
+
+	var kph float32
+	if playTimeHours > 0 {
+		kph = float32(kills) / playTimeHours
+	}
+
+	battleRanksGained := (character.Prestige*100 + character.BattleRank) - (baseline.Prestige*100 + baseline.BattleRank)
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: character.Name,
+		},
+		Title:       "Click here for full stats",
+		URL:         p.config.voidwellLink("ps2/player/%s", character.CharacterId),
+		Color:       p.embedColor(client, message.Channel(), character.FactionId),
+		Description: fmt.Sprintf("Progress since %s", baseline.SnapshotDate.Format("2006-01-02 15:04 UTC")),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{
+				Name:   "Kills",
+				Value:  fmt.Sprintf("+%d", kills),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Deaths",
+				Value:  fmt.Sprintf("+%d", deaths),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Play Time",
+				Value:  fmt.Sprintf("+%0.1f Hours", playTimeHours),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "KDR",
+				Value:  fmt.Sprintf("%0.2f (lifetime %0.2f)", kdr, character.KillDeathRatio),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "KpH",
+				Value:  fmt.Sprintf("%0.2f (%s vs lifetime)", kph, formatPercentDifference(float64(kph), float64(character.KillsPerHour))),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Score",
+				Value:  fmt.Sprintf("+%d", character.Score-baseline.Score),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Battle Ranks",
+				Value:  fmt.Sprintf("+%d (now %s)", battleRanksGained, formatBattleRank(character)),
+				Inline: true,
+			},
+		},
+		Footer: createStaleFooter(character.Stale),
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
@@ -0,0 +1,42 @@
+package planetsidetwoplugin
+
+import "testing"
+
+func TestParseProgressQuery(t *testing.T) {
+	tests := []struct {
+		text          string
+		characterName string
+		windowName    string
+		invalid       bool
+	}{
+		{"", "", "7d", false},
+		{"30d", "", "30d", false},
+		{"90D", "", "90d", false},
+		{"Foo", "Foo", "7d", false},
+		{"Foo 30d", "Foo", "30d", false},
+		{"30d Foo", "Foo", "30d", false},
+		{"<@!1234> 90d", "<@!1234>", "90d", false},
+		{"me 14d", "me", "14d", false},
+		{"Foo Bar", "", "", true},
+	}
+
+	for _, test := range tests {
+		characterName, windowName, err := parseProgressQuery(test.text)
+
+		if test.invalid {
+			if err == nil {
+				t.Errorf("parseProgressQuery(%q) = %q, %q, want an error", test.text, characterName, windowName)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseProgressQuery(%q) returned error: %s", test.text, err)
+			continue
+		}
+
+		if characterName != test.characterName || windowName != test.windowName {
+			t.Errorf("parseProgressQuery(%q) = %q, %q, want %q, %q", test.text, characterName, windowName, test.characterName, test.windowName)
+		}
+	}
+}
@@ -0,0 +1,685 @@
+package planetsidetwoplugin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	databaseDirectory = "/data/planetsidetwoplugin/"
+	databaseName      = "planetsidetwoplugin.db"
+)
+
+type repository struct {
+	Database *sql.DB
+}
+
+func newRepository() *repository {
+	return &repository{}
+}
+
+func (r *repository) initRepository() {
+	databaseDirectoryPath, _ := filepath.Abs(databaseDirectory)
+	databaseFilePath := filepath.Join(databaseDirectoryPath, databaseName)
+
+	os.MkdirAll(databaseDirectoryPath, 0755)
+
+	db, err := sql.Open("sqlite3", databaseFilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = db.Exec(initSQL)
+	if err != nil {
+		log.Fatalf("%q: %s\n", err, initSQL)
+	}
+
+	r.Database = db
+}
+
+func (r *repository) getCharacterLink(userID string) (*characterLink, error) {
+	stmt, err := r.Database.Prepare("select userId, characterId, characterName, platform, lastChangedDate from character_link where userId = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var record = &characterLink{}
+	err = stmt.QueryRow(userID).Scan(
+		&record.UserID,
+		&record.CharacterID,
+		&record.CharacterName,
+		&record.Platform,
+		&record.LastChangedDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// getGuildCharacterLinks returns the links of the users who linked their character in the guild.
+func (r *repository) getGuildCharacterLinks(guildID string) ([]*characterLink, error) {
+	rows, err := r.Database.Query("select l.userId, l.characterId, l.characterName, l.platform, l.lastChangedDate from character_link l inner join character_link_guild g on g.userId = l.userId where g.guildId = ?", guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*characterLink, 0)
+
+	for rows.Next() {
+		var record = &characterLink{}
+		err = rows.Scan(
+			&record.UserID,
+			&record.CharacterID,
+			&record.CharacterName,
+			&record.Platform,
+			&record.LastChangedDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// addCharacterLinkGuild records that the user's link should be listed in the guild.
+func (r *repository) addCharacterLinkGuild(userID string, guildID string) error {
+	stmt, err := r.Database.Prepare("insert into character_link_guild (userId, guildId) values (?,?) on conflict (userId, guildId) do nothing")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, guildID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) updateCharacterLink(userID string, characterID string, characterName string, platform string) error {
+	stmt, err := r.Database.Prepare("insert into character_link (userId, characterId, characterName, platform, lastChangedDate) values (?,?,?,?,?) on conflict (userId) do update set characterId = excluded.characterId, characterName = excluded.characterName, platform = excluded.platform, lastChangedDate = excluded.lastChangedDate")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(userID, characterID, characterName, platform, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) deleteCharacterLink(userID string) (bool, error) {
+	stmt, err := r.Database.Prepare("delete from character_link where userId = ?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = r.Database.Exec("delete from character_link_guild where userId = ?", userID)
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// trackCharacter starts tracking the character, or records that it was requested again, and
+// reports whether it was not tracked before.
+func (r *repository) trackCharacter(characterID string, platform string, characterName string) (bool, error) {
+	stmt, err := r.Database.Prepare("insert into tracked_character (characterId, platform, characterName, trackedDate, lastRequestedDate) values (?,?,?,?,?) on conflict (characterId, platform) do nothing")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	result, err := stmt.Exec(characterID, platform, characterName, now, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected > 0 {
+		return true, nil
+	}
+
+	_, err = r.Database.Exec("update tracked_character set characterName = ?, lastRequestedDate = ? where characterId = ? and platform = ?", characterName, now, characterID, platform)
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// untrackIdleCharacters stops tracking the characters nobody requested since the cutoff, keeping
+// those linked to a Discord account or on a guild roster. Their snapshots are kept, so history
+// picks up again if they are requested later.
+func (r *repository) untrackIdleCharacters(cutoff time.Time) (int64, error) {
+	result, err := r.Database.Exec(`delete from tracked_character
+		where (lastRequestedDate is null or lastRequestedDate < ?)
+		and not exists (select 1 from character_link l where l.characterId = tracked_character.characterId and l.platform = tracked_character.platform)
+		and not exists (select 1 from guild_roster g where g.characterId = tracked_character.characterId and g.platform = tracked_character.platform)`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *repository) getTrackedCharacters() ([]*trackedCharacter, error) {
+	rows, err := r.Database.Query("select characterId, platform, characterName, trackedDate, lastRequestedDate from tracked_character")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*trackedCharacter, 0)
+	for rows.Next() {
+		var record = &trackedCharacter{}
+		err = rows.Scan(
+			&record.CharacterID,
+			&record.Platform,
+			&record.CharacterName,
+			&record.TrackedDate,
+			&record.LastRequestedDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func (r *repository) insertCharacterSnapshot(snapshot *characterSnapshot) error {
+	stmt, err := r.Database.Prepare("insert into character_snapshot (characterId, platform, snapshotDate, kills, deaths, playTime, score, battleRank, prestige, killsPerHour) values (?,?,?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		snapshot.CharacterID,
+		snapshot.Platform,
+		snapshot.SnapshotDate,
+		snapshot.Kills,
+		snapshot.Deaths,
+		snapshot.PlayTime,
+		snapshot.Score,
+		snapshot.BattleRank,
+		snapshot.Prestige,
+		snapshot.KillsPerHour)
+
+	return err
+}
+
+// getEarliestSnapshotSince returns the oldest snapshot of the character taken at or after since.
+func (r *repository) getEarliestSnapshotSince(characterID string, platform string, since time.Time) (*characterSnapshot, error) {
+	return r.getSnapshot("select characterId, platform, snapshotDate, kills, deaths, playTime, score, battleRank, prestige, killsPerHour from character_snapshot where characterId = ? and platform = ? and snapshotDate >= ? order by snapshotDate asc limit 1", characterID, platform, since)
+}
+
+func (r *repository) getLatestSnapshot(characterID string, platform string) (*characterSnapshot, error) {
+	return r.getSnapshot("select characterId, platform, snapshotDate, kills, deaths, playTime, score, battleRank, prestige, killsPerHour from character_snapshot where characterId = ? and platform = ? order by snapshotDate desc limit 1", characterID, platform)
+}
+
+func (r *repository) getSnapshot(query string, args ...interface{}) (*characterSnapshot, error) {
+	stmt, err := r.Database.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var record = &characterSnapshot{}
+	err = stmt.QueryRow(args...).Scan(
+		&record.CharacterID,
+		&record.Platform,
+		&record.SnapshotDate,
+		&record.Kills,
+		&record.Deaths,
+		&record.PlayTime,
+		&record.Score,
+		&record.BattleRank,
+		&record.Prestige,
+		&record.KillsPerHour)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (r *repository) addRosterEntry(guildID string, characterID string, platform string, characterName string, userID string) error {
+	stmt, err := r.Database.Prepare("insert into guild_roster (guildId, characterId, platform, characterName, addedBy, addedDate) values (?,?,?,?,?,?) on conflict (guildId, characterId, platform) do update set characterName = excluded.characterName")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(guildID, characterID, platform, characterName, userID, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) removeRosterEntry(guildID string, characterName string, platform string) (bool, error) {
+	stmt, err := r.Database.Prepare("delete from guild_roster where guildId = ? and lower(characterName) = lower(?) and platform = ?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(guildID, characterName, platform)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) getRoster(guildID string, platform string) ([]*rosterEntry, error) {
+	rows, err := r.Database.Query("select guildId, characterId, platform, characterName, addedBy, addedDate from guild_roster where guildId = ? and platform = ?", guildID, platform)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*rosterEntry, 0)
+	for rows.Next() {
+		var record = &rosterEntry{}
+		err = rows.Scan(
+			&record.GuildID,
+			&record.CharacterID,
+			&record.Platform,
+			&record.CharacterName,
+			&record.AddedBy,
+			&record.AddedDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func (r *repository) updateRosterCharacterName(characterID string, platform string, characterName string) error {
+	_, err := r.Database.Exec("update guild_roster set characterName = ? where characterId = ? and platform = ?", characterName, characterID, platform)
+	return err
+}
+
+func (r *repository) addAlertSubscription(subscription *alertSubscription) error {
+	stmt, err := r.Database.Prepare("insert into alert_subscription (channelId, platform, worldId, guildId, roleId, createdBy, createdDate) values (?,?,?,?,?,?,?) on conflict (channelId, platform, worldId) do update set roleId = excluded.roleId")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(subscription.ChannelID, subscription.Platform, subscription.WorldID, subscription.GuildID, subscription.RoleID, subscription.CreatedBy, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) removeAlertSubscription(channelID string, platform string, worldID string) (bool, error) {
+	stmt, err := r.Database.Prepare("delete from alert_subscription where channelId = ? and platform = ? and worldId = ?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(channelID, platform, worldID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) getAlertSubscriptions() ([]*alertSubscription, error) {
+	return r.queryAlertSubscriptions("select channelId, platform, worldId, guildId, roleId, createdBy, createdDate from alert_subscription")
+}
+
+func (r *repository) getWorldAlertSubscriptions(platform string, worldID string) ([]*alertSubscription, error) {
+	return r.queryAlertSubscriptions("select channelId, platform, worldId, guildId, roleId, createdBy, createdDate from alert_subscription where platform = ? and worldId = ?", platform, worldID)
+}
+
+func (r *repository) getGuildAlertSubscriptions(guildID string) ([]*alertSubscription, error) {
+	return r.queryAlertSubscriptions("select channelId, platform, worldId, guildId, roleId, createdBy, createdDate from alert_subscription where guildId = ?", guildID)
+}
+
+func (r *repository) queryAlertSubscriptions(query string, args ...interface{}) ([]*alertSubscription, error) {
+	rows, err := r.Database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*alertSubscription, 0)
+	for rows.Next() {
+		var record = &alertSubscription{}
+		err = rows.Scan(
+			&record.ChannelID,
+			&record.Platform,
+			&record.WorldID,
+			&record.GuildID,
+			&record.RoleID,
+			&record.CreatedBy,
+			&record.CreatedDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func (r *repository) addOutfitFeed(feed *outfitFeed) error {
+	stmt, err := r.Database.Prepare("insert into outfit_feed (channelId, platform, outfitId, outfitAlias, guildId, createdBy, createdDate) values (?,?,?,?,?,?,?) on conflict (channelId, platform, outfitId) do update set outfitAlias = excluded.outfitAlias")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(feed.ChannelID, feed.Platform, feed.OutfitID, feed.OutfitAlias, feed.GuildID, feed.CreatedBy, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) removeOutfitFeed(channelID string, platform string, outfitAlias string) (*outfitFeed, error) {
+	feeds, err := r.queryOutfitFeeds("select channelId, platform, outfitId, outfitAlias, guildId, createdBy, createdDate from outfit_feed where channelId = ? and platform = ? and lower(outfitAlias) = lower(?)", channelID, platform, outfitAlias)
+	if err != nil || len(feeds) == 0 {
+		return nil, err
+	}
+
+	feed := feeds[0]
+
+	stmt, err := r.Database.Prepare("delete from outfit_feed where channelId = ? and platform = ? and outfitId = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(feed.ChannelID, feed.Platform, feed.OutfitID)
+	if err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+func (r *repository) getOutfitFeeds() ([]*outfitFeed, error) {
+	return r.queryOutfitFeeds("select channelId, platform, outfitId, outfitAlias, guildId, createdBy, createdDate from outfit_feed")
+}
+
+func (r *repository) getOutfitFeedChannels(platform string, outfitID string) ([]*outfitFeed, error) {
+	return r.queryOutfitFeeds("select channelId, platform, outfitId, outfitAlias, guildId, createdBy, createdDate from outfit_feed where platform = ? and outfitId = ?", platform, outfitID)
+}
+
+func (r *repository) queryOutfitFeeds(query string, args ...interface{}) ([]*outfitFeed, error) {
+	rows, err := r.Database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*outfitFeed, 0)
+	for rows.Next() {
+		var record = &outfitFeed{}
+		err = rows.Scan(
+			&record.ChannelID,
+			&record.Platform,
+			&record.OutfitID,
+			&record.OutfitAlias,
+			&record.GuildID,
+			&record.CreatedBy,
+			&record.CreatedDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// getCatalogWeapon returns the catalog entry for the weapon, ignoring case, or nil if it is not
+// cataloged or only seeded. Seeded weapons have no stats until their first sync.
+func (r *repository) getCatalogWeapon(weaponName string) (*PlanetsideWeapon, error) {
+	stmt, err := r.Database.Prepare("select data from weapon_catalog where name = ? and updatedDate is not null")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var data string
+	err = stmt.QueryRow(weaponName).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var weapon PlanetsideWeapon
+	err = json.Unmarshal([]byte(data), &weapon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &weapon, nil
+}
+
+// getCatalogWeapons returns every synced weapon in the catalog.
+func (r *repository) getCatalogWeapons() ([]*PlanetsideWeapon, error) {
+	rows, err := r.Database.Query("select data from weapon_catalog where updatedDate is not null order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weapons := make([]*PlanetsideWeapon, 0)
+	for rows.Next() {
+		var data string
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		var weapon PlanetsideWeapon
+		err = json.Unmarshal([]byte(data), &weapon)
+		if err != nil {
+			return nil, err
+		}
+		weapons = append(weapons, &weapon)
+	}
+
+	return weapons, rows.Err()
+}
+
+func (r *repository) getCatalogWeaponNames() ([]string, error) {
+	rows, err := r.Database.Query("select name from weapon_catalog order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+func (r *repository) countCatalogWeapons() (int, error) {
+	var count int
+	err := r.Database.QueryRow("select count(*) from weapon_catalog").Scan(&count)
+	return count, err
+}
+
+func (r *repository) upsertCatalogWeapon(weapon *PlanetsideWeapon) error {
+	data, err := json.Marshal(weapon)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.Database.Prepare("insert into weapon_catalog (name, itemId, data, updatedDate) values (?,?,?,?) on conflict (name) do update set itemId = excluded.itemId, data = excluded.data, updatedDate = excluded.updatedDate")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(weapon.Name, weapon.ItemID, string(data), now)
+
+	return err
+}
+
+func (r *repository) addPatchNoteSubscription(subscription *patchNoteSubscription) error {
+	stmt, err := r.Database.Prepare("insert into patch_note_subscription (channelId, guildId, createdBy, createdDate) values (?,?,?,?) on conflict (channelId) do nothing")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	_, err = stmt.Exec(subscription.ChannelID, subscription.GuildID, subscription.CreatedBy, now)
+
+	return err
+}
+
+func (r *repository) removePatchNoteSubscription(channelID string) (bool, error) {
+	stmt, err := r.Database.Prepare("delete from patch_note_subscription where channelId = ?")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(channelID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *repository) getPatchNoteSubscriptions() ([]*patchNoteSubscription, error) {
+	rows, err := r.Database.Query("select channelId, guildId, createdBy, createdDate from patch_note_subscription")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*patchNoteSubscription, 0)
+	for rows.Next() {
+		var record = &patchNoteSubscription{}
+		err = rows.Scan(
+			&record.ChannelID,
+			&record.GuildID,
+			&record.CreatedBy,
+			&record.CreatedDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// seedCatalogWeapon adds a weapon from the bundled catalog. Seeded weapons have no updated
+// date until their first sync.
+func (r *repository) seedCatalogWeapon(weapon *PlanetsideWeapon) error {
+	data, err := json.Marshal(weapon)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.Database.Prepare("insert into weapon_catalog (name, itemId, data, updatedDate) values (?,?,?,NULL) on conflict (name) do nothing")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(weapon.Name, weapon.ItemID, string(data))
+
+	return err
+}
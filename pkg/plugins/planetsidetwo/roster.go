@@ -0,0 +1,335 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	// rosterFetchWorkers and rosterFetchInterval bound how hard a leaderboard hits the data source.
+	rosterFetchWorkers  = 4
+	rosterFetchInterval = 200 * time.Millisecond
+	leaderboardPageSize = 10
+	defaultMinimumHours = 5
+)
+
+type leaderboardMetric struct {
+	label  string
+	value  func(character *PlanetsideCharacter) float64
+	format func(character *PlanetsideCharacter) string
+}
+
+var leaderboardMetrics = map[string]leaderboardMetric{
+	"kdr": {
+		label:  "KDR",
+		value:  func(c *PlanetsideCharacter) float64 { return float64(c.KillDeathRatio) },
+		format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", c.KillDeathRatio) },
+	},
+	"kph": {
+		label:  "KpH",
+		value:  func(c *PlanetsideCharacter) float64 { return float64(c.KillsPerHour) },
+		format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f", c.KillsPerHour) },
+	},
+	"hsr": {
+		label:  "HSR",
+		value:  func(c *PlanetsideCharacter) float64 { return float64(c.HeadshotRatio) },
+		format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.2f%%", c.HeadshotRatio*100) },
+	},
+	"ivi": {
+		label:  "IVI Score",
+		value:  func(c *PlanetsideCharacter) float64 { return float64(c.IVIScore) },
+		format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%d", c.IVIScore) },
+	},
+	"score": {
+		label:  "Score",
+		value:  func(c *PlanetsideCharacter) float64 { return float64(c.Score) },
+		format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%d", c.Score) },
+	},
+	"playtime": {
+		label:  "Play Time",
+		value:  func(c *PlanetsideCharacter) float64 { return float64(c.PlayTime) },
+		format: func(c *PlanetsideCharacter) string { return fmt.Sprintf("%0.1f Hours", float32(c.PlayTime)/3600.0) },
+	},
+}
+
+type leaderboardOptions struct {
+	metric       string
+	platform     string
+	page         int
+	minimumHours int
+}
+
+// parseLeaderboardOptions reads the stat, positional platform and 'key=value' options in any order.
+func parseLeaderboardOptions(text string) (*leaderboardOptions, error) {
+	options := &leaderboardOptions{
+		metric:       "kdr",
+		page:         1,
+		minimumHours: defaultMinimumHours,
+	}
+
+	for _, token := range strings.Fields(text) {
+		separator := strings.Index(token, "=")
+		if separator < 0 {
+			if _, ok := leaderboardMetrics[strings.ToLower(token)]; ok {
+				options.metric = strings.ToLower(token)
+			} else if _, ok := parsePlatform(token); ok {
+				options.platform = token
+			} else {
+				return nil, fmt.Errorf("Unknown stat '%s'. Use kdr, kph, hsr, ivi, score or playtime.", token)
+			}
+			continue
+		}
+
+		key, value := strings.ToLower(token[:separator]), token[separator+1:]
+
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("Invalid %s '%s'.", key, value)
+		}
+
+		switch key {
+		case "page":
+			if number < 1 {
+				return nil, fmt.Errorf("Invalid page '%s'.", value)
+			}
+			options.page = number
+		case "minhours":
+			options.minimumHours = number
+		default:
+			return nil, fmt.Errorf("Unknown option '%s'. Use page or minhours.", key)
+		}
+	}
+
+	return options, nil
+}
+
+func (p *planetsidetwoPlugin) runRosterCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "The roster can only be managed in a server.")
+		p.RUnlock()
+		return
+	}
+
+	characterName := args["characterName"]
+
+	if strings.ToLower(args["action"]) == "remove" {
+		removed, err := p.repository.removeRosterEntry(channel.GuildID, characterName, platform)
+
+		p.RLock()
+
+		if err != nil {
+			client.SendMessage(message.Channel(), "Failed to remove character from the roster.")
+		} else if !removed {
+			client.SendMessage(message.Channel(), fmt.Sprintf("%s is not on the %s roster.", characterName, platformDisplayName(platform)))
+		} else {
+			client.SendMessage(message.Channel(), fmt.Sprintf("Removed %s from the roster!", characterName))
+		}
+
+		p.RUnlock()
+		return
+	}
+
+	character, err := p.dataSource.GetCharacter(characterName, platform)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	go p.trackCharacter(character)
+
+	err = p.repository.addRosterEntry(channel.GuildID, character.CharacterId, platform, character.Name, message.UserID())
+
+	p.RLock()
+
+	if err != nil {
+		client.SendMessage(message.Channel(), "Failed to add character to the roster.")
+	} else {
+		client.SendMessage(message.Channel(), fmt.Sprintf("Added %s (%s) to the roster!", character.Name, platformDisplayName(platform)))
+	}
+
+	p.RUnlock()
+}
+
+func (p *planetsidetwoPlugin) runLeaderboardCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	options, err := parseLeaderboardOptions(platformFlagPattern.ReplaceAllString(args["options"], " "))
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	args["platform"] = options.platform
+
+	platform, err := p.resolvePlatform(client, payload)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	metric := leaderboardMetrics[options.metric]
+
+	channel, err := client.Channel(message.Channel())
+	if err != nil || channel.GuildID == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Leaderboards can only be shown in a server.")
+		p.RUnlock()
+		return
+	}
+
+	roster, err := p.repository.getRoster(channel.GuildID, platform)
+	if err != nil {
+		log.Printf("Failed to get roster for guild '%s': %s", channel.GuildID, err)
+		p.RLock()
+		client.SendMessage(message.Channel(), "Failed to get the roster.")
+		p.RUnlock()
+		return
+	}
+
+	if len(roster) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("The %s roster is empty. Use ps2roster add <character name> to add characters.", platformDisplayName(platform)))
+		p.RUnlock()
+		return
+	}
+
+	characters := p.fetchRosterCharacters(roster)
+
+	minimumPlayTime := options.minimumHours * 3600
+	ranked := make([]*PlanetsideCharacter, 0, len(characters))
+	stale := false
+	for _, character := range characters {
+		if character.PlayTime < minimumPlayTime {
+			continue
+		}
+		stale = stale || character.Stale
+		ranked = append(ranked, character)
+	}
+
+	if len(ranked) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("No roster characters have at least %d hours played.", options.minimumHours))
+		p.RUnlock()
+		return
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return metric.value(ranked[i]) > metric.value(ranked[j])
+	})
+
+	lines := make([]string, len(ranked))
+	for i, character := range ranked {
+		lines[i] = fmt.Sprintf("**%d.** %s: %s", i+1, character.Name, metric.format(character))
+	}
+
+	title := fmt.Sprintf("%s leaderboard (%s)", metric.label, platformDisplayName(platform))
+	excluded := len(roster) - len(ranked)
+
+	color := p.embedColor(client, message.Channel(), 0)
+
+	pages := paginateLines(lines, leaderboardPageSize, func(description string) *discordgo.MessageEmbed {
+		embed := &discordgo.MessageEmbed{
+			Title:       title,
+			Color:       color,
+			Description: description,
+			Footer:      createStaleFooter(stale),
+		}
+
+		if excluded > 0 {
+			embed.Fields = []*discordgo.MessageEmbedField{
+				&discordgo.MessageEmbedField{
+					Name:  "Excluded",
+					Value: fmt.Sprintf("%d characters unavailable or under %d hours played", excluded, options.minimumHours),
+				},
+			}
+		}
+
+		return embed
+	})
+
+	p.RLock()
+	err = p.pagination.send(client, message.Channel(), message.UserID(), pages, options.page-1)
+	p.RUnlock()
+
+	if err != nil {
+		log.Printf("Failed to send leaderboard: %s", err)
+	}
+}
+
+// fetchRosterCharacters looks up every roster entry with a small pool of workers sharing a
+// single rate limit. Characters that fail to load are left out.
+func (p *planetsidetwoPlugin) fetchRosterCharacters(roster []*rosterEntry) []*PlanetsideCharacter {
+	limiter := time.NewTicker(rosterFetchInterval)
+	defer limiter.Stop()
+
+	entries := make(chan *rosterEntry)
+	results := make([]*PlanetsideCharacter, len(roster))
+	indexes := make(map[*rosterEntry]int, len(roster))
+	for i, entry := range roster {
+		indexes[entry] = i
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < rosterFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range entries {
+				<-limiter.C
+
+				character, err := p.dataSource.GetCharacterByID(entry.CharacterID, entry.Platform)
+				if err != nil {
+					log.Printf("Failed to get roster character '%s': %s", entry.CharacterID, err)
+					continue
+				}
+
+				if character.Name != "" && character.Name != entry.CharacterName {
+					p.repository.updateRosterCharacterName(entry.CharacterID, entry.Platform, character.Name)
+				}
+
+				results[indexes[entry]] = character
+			}
+		}()
+	}
+
+	for _, entry := range roster {
+		entries <- entry
+	}
+	close(entries)
+	wg.Wait()
+
+	characters := make([]*PlanetsideCharacter, 0, len(results))
+	for _, character := range results {
+		if character != nil {
+			characters = append(characters, character)
+		}
+	}
+
+	return characters
+}
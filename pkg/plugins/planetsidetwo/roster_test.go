@@ -0,0 +1,41 @@
+package planetsidetwoplugin
+
+import "testing"
+
+func TestParseLeaderboardOptions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected leaderboardOptions
+		invalid  bool
+	}{
+		{"", leaderboardOptions{metric: "kdr", page: 1, minimumHours: defaultMinimumHours}, false},
+		{"KPH", leaderboardOptions{metric: "kph", page: 1, minimumHours: defaultMinimumHours}, false},
+		{"ps4eu", leaderboardOptions{metric: "kdr", platform: "ps4eu", page: 1, minimumHours: defaultMinimumHours}, false},
+		{"page=2 minhours=10", leaderboardOptions{metric: "kdr", page: 2, minimumHours: 10}, false},
+		{"minhours=0 score ps4-us", leaderboardOptions{metric: "score", platform: "ps4-us", page: 1, minimumHours: 0}, false},
+		{"deaths", leaderboardOptions{}, true},
+		{"page=0", leaderboardOptions{}, true},
+		{"page=two", leaderboardOptions{}, true},
+		{"sort=kdr", leaderboardOptions{}, true},
+	}
+
+	for _, test := range tests {
+		options, err := parseLeaderboardOptions(test.text)
+
+		if test.invalid {
+			if err == nil {
+				t.Errorf("parseLeaderboardOptions(%q) = %+v, want an error", test.text, *options)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseLeaderboardOptions(%q) returned error: %s", test.text, err)
+			continue
+		}
+
+		if *options != test.expected {
+			t.Errorf("parseLeaderboardOptions(%q) = %+v, want %+v", test.text, *options, test.expected)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	selectionTimeout    = 60 * time.Second
+	selectionMaxOptions = 5
+)
+
+var selectionEmojis = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣"}
+
+// pendingSelection is a numbered list posted to a channel waiting for its invoker to pick an option.
+type pendingSelection struct {
+	channelID string
+	messageID string
+	userID    string
+	options   []string
+	onSelect  func(option string)
+	timer     *time.Timer
+}
+
+// selectionManager tracks pending selections and resolves them from reactions or numeric replies.
+// Each user has at most one pending selection per channel, so a numeric reply can only answer the
+// list that user was asked.
+type selectionManager struct {
+	sync.Mutex
+	pending      map[string]*pendingSelection
+	invokers     map[string]*pendingSelection
+	handlersOnce sync.Once
+}
+
+func newSelectionManager() *selectionManager {
+	return &selectionManager{
+		pending:  make(map[string]*pendingSelection),
+		invokers: make(map[string]*pendingSelection),
+	}
+}
+
+func selectionInvokerKey(channelID string, userID string) string {
+	return channelID + "/" + userID
+}
+
+// prompt posts the options as a numbered list and calls onSelect with the option the user picks.
+func (m *selectionManager) prompt(client *discordgobot.DiscordClient, channelID string, userID string, title string, color int, options []string, onSelect func(option string)) error {
+	if len(client.Sessions) == 0 {
+		return fmt.Errorf("No Discord session available")
+	}
+
+	m.registerHandlers(client)
+
+	if len(options) > selectionMaxOptions {
+		options = options[:selectionMaxOptions]
+	}
+
+	lines := make([]string, len(options))
+	for i, option := range options {
+		lines[i] = fmt.Sprintf("%s %s", selectionEmojis[i], option)
+	}
+
+	session := client.Sessions[0]
+
+	sent, err := session.ChannelMessageSendEmbed(channelID, &discordgo.MessageEmbed{
+		Title:       title,
+		Color:       color,
+		Description: strings.Join(lines, "\n"),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "React or reply with a number to choose.",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	selection := &pendingSelection{
+		channelID: channelID,
+		messageID: sent.ID,
+		userID:    userID,
+		options:   options,
+		onSelect:  onSelect,
+	}
+
+	selection.timer = time.AfterFunc(selectionTimeout, func() {
+		if m.remove(selection.messageID) != nil {
+			session.ChannelMessageDelete(selection.channelID, selection.messageID)
+		}
+	})
+
+	// A new prompt replaces the one the user left unanswered in the channel.
+	if previous := m.add(selection); previous != nil && m.remove(previous.messageID) != nil {
+		previous.timer.Stop()
+		session.ChannelMessageDelete(previous.channelID, previous.messageID)
+	}
+
+	for i := range options {
+		err = session.MessageReactionAdd(channelID, sent.ID, selectionEmojis[i])
+		if err != nil {
+			log.Printf("Failed to add selection reaction: %s", err)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *selectionManager) registerHandlers(client *discordgobot.DiscordClient) {
+	m.handlersOnce.Do(func() {
+		for _, session := range client.Sessions {
+			session.AddHandler(m.onReactionAdd)
+			session.AddHandler(m.onMessageCreate)
+		}
+	})
+}
+
+func (m *selectionManager) onReactionAdd(session *discordgo.Session, reaction *discordgo.MessageReactionAdd) {
+	m.Lock()
+	selection, ok := m.pending[reaction.MessageID]
+	m.Unlock()
+
+	if !ok || reaction.UserID != selection.userID {
+		return
+	}
+
+	for i, emoji := range selectionEmojis {
+		if emoji == reaction.Emoji.Name && i < len(selection.options) {
+			m.choose(session, selection, i)
+			return
+		}
+	}
+}
+
+func (m *selectionManager) onMessageCreate(session *discordgo.Session, message *discordgo.MessageCreate) {
+	if message.Author == nil {
+		return
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(message.Content))
+	if err != nil {
+		return
+	}
+
+	m.Lock()
+	selection := m.invokers[selectionInvokerKey(message.ChannelID, message.Author.ID)]
+	m.Unlock()
+
+	if selection == nil || choice < 1 || choice > len(selection.options) {
+		return
+	}
+
+	m.choose(session, selection, choice-1)
+}
+
+func (m *selectionManager) choose(session *discordgo.Session, selection *pendingSelection, index int) {
+	if m.remove(selection.messageID) == nil {
+		return
+	}
+
+	selection.timer.Stop()
+	session.ChannelMessageDelete(selection.channelID, selection.messageID)
+
+	selection.onSelect(selection.options[index])
+}
+
+// add registers the selection, returning the selection it replaced for the same channel and user.
+func (m *selectionManager) add(selection *pendingSelection) *pendingSelection {
+	m.Lock()
+	defer m.Unlock()
+
+	key := selectionInvokerKey(selection.channelID, selection.userID)
+	previous := m.invokers[key]
+
+	m.pending[selection.messageID] = selection
+	m.invokers[key] = selection
+
+	return previous
+}
+
+// remove drops the pending selection and returns it, or nil when it was already resolved.
+func (m *selectionManager) remove(messageID string) *pendingSelection {
+	m.Lock()
+	defer m.Unlock()
+
+	selection, ok := m.pending[messageID]
+	if !ok {
+		return nil
+	}
+
+	delete(m.pending, messageID)
+
+	key := selectionInvokerKey(selection.channelID, selection.userID)
+	if m.invokers[key] == selection {
+		delete(m.invokers, key)
+	}
+
+	return selection
+}
@@ -0,0 +1,52 @@
+package planetsidetwoplugin
+
+import (
+	"testing"
+)
+
+func TestSelectionManagerKeysByChannelAndUser(t *testing.T) {
+	manager := newSelectionManager()
+
+	first := &pendingSelection{channelID: "c1", messageID: "m1", userID: "u1"}
+	otherUser := &pendingSelection{channelID: "c1", messageID: "m2", userID: "u2"}
+	otherChannel := &pendingSelection{channelID: "c2", messageID: "m3", userID: "u1"}
+	replacement := &pendingSelection{channelID: "c1", messageID: "m4", userID: "u1"}
+
+	for _, selection := range []*pendingSelection{first, otherUser, otherChannel} {
+		if previous := manager.add(selection); previous != nil {
+			t.Errorf("add(%s) replaced %s, want nothing", selection.messageID, previous.messageID)
+		}
+	}
+
+	if previous := manager.add(replacement); previous != first {
+		t.Errorf("add(%s) replaced %v, want %s", replacement.messageID, previous, first.messageID)
+	}
+
+	// The replaced selection is removed by the caller, which must not drop its replacement.
+	manager.remove(first.messageID)
+
+	tests := []struct {
+		channelID string
+		userID    string
+		expected  *pendingSelection
+	}{
+		{"c1", "u1", replacement},
+		{"c1", "u2", otherUser},
+		{"c2", "u1", otherChannel},
+		{"c2", "u2", nil},
+	}
+
+	for _, test := range tests {
+		if actual := manager.invokers[selectionInvokerKey(test.channelID, test.userID)]; actual != test.expected {
+			t.Errorf("selection for %s in %s = %v, want %v", test.userID, test.channelID, actual, test.expected)
+		}
+	}
+
+	if manager.remove(replacement.messageID) != replacement || manager.remove(replacement.messageID) != nil {
+		t.Errorf("remove(%s) did not remove the selection exactly once", replacement.messageID)
+	}
+
+	if _, ok := manager.invokers[selectionInvokerKey("c1", "u1")]; ok {
+		t.Error("remove left the user's selection waiting for a reply")
+	}
+}
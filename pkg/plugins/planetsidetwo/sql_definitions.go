@@ -0,0 +1,154 @@
+package planetsidetwoplugin
+
+import "time"
+
+const initSQL = `
+CREATE TABLE IF NOT EXISTS character_link (
+	userId TEXT NOT NULL PRIMARY KEY,
+	characterId TEXT NOT NULL,
+	characterName TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	lastChangedDate TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS character_link_guild (
+	userId TEXT NOT NULL,
+	guildId TEXT NOT NULL,
+	PRIMARY KEY (userId, guildId)
+);
+
+CREATE TABLE IF NOT EXISTS tracked_character (
+	characterId TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	characterName TEXT NOT NULL,
+	trackedDate TIMESTAMP,
+	lastRequestedDate TIMESTAMP,
+	PRIMARY KEY (characterId, platform)
+);
+
+CREATE TABLE IF NOT EXISTS character_snapshot (
+	characterId TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	snapshotDate TIMESTAMP NOT NULL,
+	kills INTEGER NOT NULL,
+	deaths INTEGER NOT NULL,
+	playTime INTEGER NOT NULL,
+	score INTEGER NOT NULL,
+	battleRank INTEGER NOT NULL,
+	prestige INTEGER NOT NULL,
+	killsPerHour REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS character_snapshot_character_date ON character_snapshot (characterId, platform, snapshotDate);
+
+CREATE TABLE IF NOT EXISTS guild_roster (
+	guildId TEXT NOT NULL,
+	characterId TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	characterName TEXT NOT NULL,
+	addedBy TEXT,
+	addedDate TIMESTAMP,
+	PRIMARY KEY (guildId, characterId, platform)
+);
+
+CREATE TABLE IF NOT EXISTS alert_subscription (
+	channelId TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	worldId TEXT NOT NULL,
+	guildId TEXT NOT NULL,
+	roleId TEXT,
+	createdBy TEXT,
+	createdDate TIMESTAMP,
+	PRIMARY KEY (channelId, platform, worldId)
+);
+
+CREATE TABLE IF NOT EXISTS outfit_feed (
+	channelId TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	outfitId TEXT NOT NULL,
+	outfitAlias TEXT NOT NULL,
+	guildId TEXT NOT NULL,
+	createdBy TEXT,
+	createdDate TIMESTAMP,
+	PRIMARY KEY (channelId, platform, outfitId)
+);
+
+CREATE TABLE IF NOT EXISTS patch_note_subscription (
+	channelId TEXT NOT NULL PRIMARY KEY,
+	guildId TEXT NOT NULL,
+	createdBy TEXT,
+	createdDate TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS weapon_catalog (
+	name TEXT NOT NULL PRIMARY KEY COLLATE NOCASE,
+	itemId INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	updatedDate TIMESTAMP
+);
+`
+
+type characterLink struct {
+	UserID          string
+	CharacterID     string
+	CharacterName   string
+	Platform        string
+	LastChangedDate *time.Time
+}
+
+type trackedCharacter struct {
+	CharacterID       string
+	Platform          string
+	CharacterName     string
+	TrackedDate       *time.Time
+	LastRequestedDate *time.Time
+}
+
+type characterSnapshot struct {
+	CharacterID  string
+	Platform     string
+	SnapshotDate time.Time
+	Kills        int
+	Deaths       int
+	PlayTime     int
+	Score        int
+	BattleRank   int
+	Prestige     int
+	KillsPerHour float32
+}
+
+type rosterEntry struct {
+	GuildID       string
+	CharacterID   string
+	Platform      string
+	CharacterName string
+	AddedBy       *string
+	AddedDate     *time.Time
+}
+
+type alertSubscription struct {
+	ChannelID   string
+	Platform    string
+	WorldID     string
+	GuildID     string
+	RoleID      *string
+	CreatedBy   *string
+	CreatedDate *time.Time
+}
+
+type patchNoteSubscription struct {
+	ChannelID   string
+	GuildID     string
+	CreatedBy   *string
+	CreatedDate *time.Time
+}
+
+type outfitFeed struct {
+	ChannelID   string
+	Platform    string
+	OutfitID    string
+	OutfitAlias string
+	GuildID     string
+	CreatedBy   *string
+	CreatedDate *time.Time
+}
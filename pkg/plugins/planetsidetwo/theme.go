@@ -0,0 +1,54 @@
+package planetsidetwoplugin
+
+import (
+	"log"
+	"strings"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+// defaultHouseColor colors embeds that are not about a single faction.
+const defaultHouseColor = 0x070707
+
+// embedColor returns the color for an embed sent to channelID about factionID, taking the
+// guild's theme into account. A factionID of 0 asks for the guild's house color.
+func (p *planetsidetwoPlugin) embedColor(client *discordgobot.DiscordClient, channelID string, factionID int) int {
+	channel, err := client.Channel(channelID)
+	if err != nil || channel.GuildID == "" {
+		return themeColor(nil, factionID)
+	}
+
+	return p.guildEmbedColor(channel.GuildID, factionID)
+}
+
+func (p *planetsidetwoPlugin) guildEmbedColor(guildID string, factionID int) int {
+	if p.settings == nil {
+		return themeColor(nil, factionID)
+	}
+
+	theme, err := p.settings.GetGuildTheme(guildID)
+	if err != nil {
+		log.Printf("Failed to get theme for guild '%s': %s", guildID, err)
+	}
+
+	return themeColor(theme, factionID)
+}
+
+func themeColor(theme map[string]int, factionID int) int {
+	// Guild themes key faction colors by the faction's lower cased code.
+	key := "house"
+	faction, isFaction := planetsideMetadata.faction(factionID)
+	if isFaction {
+		key = strings.ToLower(faction.Code)
+	}
+
+	if color, ok := theme[key]; ok {
+		return color
+	}
+
+	if isFaction {
+		return faction.color
+	}
+
+	return defaultHouseColor
+}
@@ -0,0 +1,135 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+)
+
+const defaultTargetProfile = "infantry"
+
+// targetProfile describes what a weapon has to chew through to kill a target.
+type targetProfile struct {
+	Description        string  `json:"description"`
+	Health             float64 `json:"health"`
+	Shield             float64 `json:"shield"`
+	DamageResistance   float64 `json:"damageResistance"`
+	HeadshotMultiplier float64 `json:"headshotMultiplier"`
+}
+
+// targetProfiles is the table of targets known to the TTK calculations. Operators can
+// add or override entries with a JSON file named by the PS2TargetProfiles variable.
+var targetProfiles = map[string]*targetProfile{
+	"infantry": &targetProfile{
+		Description:        "Standard infantry",
+		Health:             500,
+		Shield:             500,
+		HeadshotMultiplier: 2,
+	},
+	"heavy-overshield": &targetProfile{
+		Description:        "Heavy Assault with overshield active",
+		Health:             500,
+		Shield:             950,
+		HeadshotMultiplier: 2,
+	},
+	"max": &targetProfile{
+		Description:        "MAX unit",
+		Health:             2000,
+		Shield:             0,
+		DamageResistance:   0.2,
+		HeadshotMultiplier: 1,
+	},
+}
+
+func (t *targetProfile) totalHealth() float64 {
+	return t.Health + t.Shield
+}
+
+// effectiveDamage is the average damage a shot deals to the target when headshotRatio of shots hit the head.
+func (t *targetProfile) effectiveDamage(damage float64, headshotRatio float64, headshotMultiplier float64) float64 {
+	average := damage * (1 + headshotRatio*(headshotMultiplier-1))
+	return average * (1 - t.DamageResistance)
+}
+
+func loadTargetProfiles(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var profiles map[string]*targetProfile
+	err = json.Unmarshal(data, &profiles)
+	if err != nil {
+		return err
+	}
+
+	for name, profile := range profiles {
+		if profile.HeadshotMultiplier == 0 {
+			profile.HeadshotMultiplier = 1
+		}
+		targetProfiles[strings.ToLower(name)] = profile
+	}
+
+	return nil
+}
+
+func targetProfileNames() []string {
+	names := make([]string, 0, len(targetProfiles))
+	for name := range targetProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// damageAtRange linearly interpolates a weapon's damage between its max and min damage ranges.
+func damageAtRange(weapon *PlanetsideWeapon, distance float64) float64 {
+	maxRange, minRange := float64(weapon.MaxDamageRange), float64(weapon.MinDamageRange)
+
+	if distance <= maxRange {
+		return float64(weapon.MaxDamage)
+	}
+
+	if distance >= minRange || minRange <= maxRange {
+		return float64(weapon.MinDamage)
+	}
+
+	progress := (distance - maxRange) / (minRange - maxRange)
+
+	return float64(weapon.MaxDamage) + progress*float64(weapon.MinDamage-weapon.MaxDamage)
+}
+
+// shotsToKill returns how many hits dealing damage are needed to deplete health.
+func shotsToKill(damage float64, health float64) int {
+	if damage <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(health / damage))
+}
+
+// timeToKill returns the milliseconds between the first and the killing shot.
+func timeToKill(shots int, fireRateMs int) int {
+	if shots <= 0 {
+		return 0
+	}
+
+	return (shots - 1) * fireRateMs
+}
+
+func formatTimeToKill(damage float64, health float64, fireRateMs int) string {
+	shots := shotsToKill(damage, health)
+	if shots == 0 {
+		return "n/a"
+	}
+
+	if fireRateMs <= 0 {
+		return fmt.Sprintf("%d shots", shots)
+	}
+
+	return fmt.Sprintf("%d shots / %d ms", shots, timeToKill(shots, fireRateMs))
+}
@@ -0,0 +1,122 @@
+package planetsidetwoplugin
+
+import (
+	"testing"
+)
+
+func TestDamageAtRange(t *testing.T) {
+	weapon := &PlanetsideWeapon{MaxDamage: 143, MaxDamageRange: 10, MinDamage: 125, MinDamageRange: 65}
+	flat := &PlanetsideWeapon{MaxDamage: 143, MaxDamageRange: 10, MinDamage: 125, MinDamageRange: 10}
+
+	tests := []struct {
+		weapon   *PlanetsideWeapon
+		distance float64
+		expected float64
+	}{
+		{weapon, 0, 143},
+		{weapon, 10, 143},
+		{weapon, 37.5, 134},
+		{weapon, 65, 125},
+		{weapon, 300, 125},
+		{flat, 5, 143},
+		{flat, 20, 125},
+	}
+
+	for _, test := range tests {
+		if actual := damageAtRange(test.weapon, test.distance); actual != test.expected {
+			t.Errorf("damageAtRange(%d@%dm-%d@%dm, %v) = %v, want %v", test.weapon.MaxDamage, test.weapon.MaxDamageRange, test.weapon.MinDamage, test.weapon.MinDamageRange, test.distance, actual, test.expected)
+		}
+	}
+}
+
+func TestEffectiveDamage(t *testing.T) {
+	tests := []struct {
+		target             string
+		damage             float64
+		headshotRatio      float64
+		headshotMultiplier float64
+		expected           float64
+	}{
+		{"infantry", 143, 0, 2, 143},
+		{"infantry", 100, 0.5, 2, 150},
+		{"infantry", 100, 1, 2, 200},
+		{"max", 100, 0, 1, 80},
+		{"max", 100, 1, 2, 160},
+	}
+
+	for _, test := range tests {
+		actual := targetProfiles[test.target].effectiveDamage(test.damage, test.headshotRatio, test.headshotMultiplier)
+		if actual != test.expected {
+			t.Errorf("%s.effectiveDamage(%v, %v, %v) = %v, want %v", test.target, test.damage, test.headshotRatio, test.headshotMultiplier, actual, test.expected)
+		}
+	}
+}
+
+func TestShotsAndTimeToKill(t *testing.T) {
+	tests := []struct {
+		damage        float64
+		health        float64
+		fireRateMs    int
+		expectedShots int
+		expectedTTK   int
+		expected      string
+	}{
+		{143, 1000, 75, 7, 450, "7 shots / 450 ms"},
+		{125, 1000, 75, 8, 525, "8 shots / 525 ms"},
+		{1000, 1000, 75, 1, 0, "1 shots / 0 ms"},
+		{1200, 1000, 0, 1, 0, "1 shots"},
+		{334, 1450, 0, 5, 0, "5 shots"},
+		{0, 1000, 75, 0, 0, "n/a"},
+	}
+
+	for _, test := range tests {
+		shots := shotsToKill(test.damage, test.health)
+		if shots != test.expectedShots {
+			t.Errorf("shotsToKill(%v, %v) = %d, want %d", test.damage, test.health, shots, test.expectedShots)
+		}
+
+		if ttk := timeToKill(shots, test.fireRateMs); ttk != test.expectedTTK {
+			t.Errorf("timeToKill(%d, %d) = %d, want %d", shots, test.fireRateMs, ttk, test.expectedTTK)
+		}
+
+		if actual := formatTimeToKill(test.damage, test.health, test.fireRateMs); actual != test.expected {
+			t.Errorf("formatTimeToKill(%v, %v, %d) = %q, want %q", test.damage, test.health, test.fireRateMs, actual, test.expected)
+		}
+	}
+}
+
+func TestParseTTKOptions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected *ttkOptions
+		err      string
+	}{
+		{"Gauss SAW", &ttkOptions{weaponName: "Gauss SAW", target: "infantry"}, ""},
+		{"Gauss SAW range=40m target=MAX", &ttkOptions{weaponName: "Gauss SAW", distance: 40, target: "max"}, ""},
+		{"headshots=25% Gauss hsmult=2x SAW", &ttkOptions{weaponName: "Gauss SAW", target: "infantry", headshotRatio: 0.25, headshotMultiplier: 2}, ""},
+		{"range=40", nil, "Please provide a weapon name."},
+		{"Gauss SAW range=-1", nil, "Invalid range '-1'."},
+		{"Gauss SAW headshots=120", nil, "Invalid headshot percentage '120'."},
+		{"Gauss SAW hsmult=0.5", nil, "Invalid headshot multiplier '0.5'."},
+		{"Gauss SAW speed=fast", nil, "Unknown option 'speed'."},
+	}
+
+	for _, test := range tests {
+		actual, err := parseTTKOptions(test.text)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("parseTTKOptions(%q) returned error %v, want %q", test.text, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseTTKOptions(%q) returned error %v", test.text, err)
+			continue
+		}
+
+		if *actual != *test.expected {
+			t.Errorf("parseTTKOptions(%q) = %+v, want %+v", test.text, *actual, *test.expected)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package planetsidetwoplugin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+type ttkOptions struct {
+	weaponName         string
+	distance           float64
+	target             string
+	headshotRatio      float64
+	headshotMultiplier float64
+}
+
+// parseTTKOptions splits 'key=value' options off the free text, leaving the weapon name.
+func parseTTKOptions(text string) (*ttkOptions, error) {
+	options := &ttkOptions{
+		target: defaultTargetProfile,
+	}
+
+	nameParts := make([]string, 0)
+
+	for _, token := range strings.Fields(text) {
+		separator := strings.Index(token, "=")
+		if separator < 0 {
+			nameParts = append(nameParts, token)
+			continue
+		}
+
+		key, value := strings.ToLower(token[:separator]), token[separator+1:]
+
+		switch key {
+		case "range":
+			distance, err := strconv.ParseFloat(strings.TrimSuffix(value, "m"), 64)
+			if err != nil || distance < 0 {
+				return nil, fmt.Errorf("Invalid range '%s'.", value)
+			}
+			options.distance = distance
+		case "target":
+			options.target = strings.ToLower(value)
+		case "headshots":
+			ratio, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || ratio < 0 || ratio > 100 {
+				return nil, fmt.Errorf("Invalid headshot percentage '%s'.", value)
+			}
+			options.headshotRatio = ratio / 100
+		case "hsmult":
+			multiplier, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+			if err != nil || multiplier < 1 {
+				return nil, fmt.Errorf("Invalid headshot multiplier '%s'.", value)
+			}
+			options.headshotMultiplier = multiplier
+		default:
+			return nil, fmt.Errorf("Unknown option '%s'.", key)
+		}
+	}
+
+	options.weaponName = strings.Join(nameParts, " ")
+	if options.weaponName == "" {
+		return nil, errors.New("Please provide a weapon name.")
+	}
+
+	return options, nil
+}
+
+func (p *planetsidetwoPlugin) runTimeToKillCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	options, err := parseTTKOptions(args["query"])
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	target, ok := targetProfiles[options.target]
+	if !ok {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("Unknown target '%s'. Known targets: %s", options.target, strings.Join(targetProfileNames(), ", ")))
+		p.RUnlock()
+		return
+	}
+
+	headshotMultiplier := target.HeadshotMultiplier
+	if options.headshotMultiplier > 0 {
+		headshotMultiplier = options.headshotMultiplier
+	}
+
+	weapon, err := p.getWeapon(options.weaponName)
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	damage := damageAtRange(weapon, options.distance)
+	effectiveDamage := target.effectiveDamage(damage, options.headshotRatio, headshotMultiplier)
+	shots := shotsToKill(effectiveDamage, target.totalHealth())
+
+	ttk := "n/a"
+	if shots > 0 && weapon.FireRateMs > 0 {
+		ttk = fmt.Sprintf("%d ms", timeToKill(shots, weapon.FireRateMs))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: weapon.Name,
+		},
+		Color: p.embedColor(client, message.Channel(), weapon.FactionID),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{
+				Name:   "Target",
+				Value:  fmt.Sprintf("%s (%0.f health)", target.Description, target.totalHealth()),
+				Inline: false,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Range",
+				Value:  fmt.Sprintf("%0.fm", options.distance),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Damage",
+				Value:  fmt.Sprintf("%0.1f (%0.1f effective)", damage, effectiveDamage),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Headshots",
+				Value:  fmt.Sprintf("%0.f%% at %0.2fx", options.headshotRatio*100, headshotMultiplier),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Shots to kill",
+				Value:  fmt.Sprintf("%d", shots),
+				Inline: true,
+			},
+			&discordgo.MessageEmbedField{
+				Name:   "Time to kill",
+				Value:  ttk,
+				Inline: true,
+			},
+		},
+		Footer: createStaleFooter(weapon.Stale),
+	}
+
+	if weapon.ImageID > 0 {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: p.config.censusImage(weapon.ImageID),
+		}
+	}
+
+	p.RLock()
+	client.SendEmbedMessage(message.Channel(), embed)
+	p.RUnlock()
+}
@@ -0,0 +1,373 @@
+package planetsidetwoplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	voidwellRequestTimeout = 15 * time.Second
+	voidwellMaxAttempts    = 3
+	voidwellRetryBackoff   = 500 * time.Millisecond
+	// voidwellMaxRetryDelay caps how long a Retry-After is honored before giving up on the request.
+	voidwellMaxRetryDelay = 10 * time.Second
+)
+
+// voidwellClient makes authenticated Voidwell API calls. Responses are cached, failed calls are
+// retried when Voidwell is overloaded, and identical concurrent calls share one request.
+type voidwellClient struct {
+	http     *http.Client
+	cache    *responseCache
+	requests singleflight.Group
+	config   *serviceConfig
+}
+
+type voidwellResponse struct {
+	body  json.RawMessage
+	stale bool
+}
+
+func newVoidwellClient(config *serviceConfig) *voidwellClient {
+	credentials := clientcredentials.Config{
+		ClientID:     config.VoidwellClientID,
+		ClientSecret: config.VoidwellClientSecret,
+		TokenURL:     config.VoidwellTokenURI,
+		Scopes:       config.VoidwellScopes,
+	}
+
+	// Token requests are made with the client in the context, so they get a timeout too.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: voidwellRequestTimeout})
+
+	return &voidwellClient{
+		http:   credentials.Client(ctx),
+		cache:  newResponseCache(),
+		config: config,
+	}
+}
+
+type voidwellDataSource struct {
+	client *voidwellClient
+}
+
+func newVoidwellDataSource(client *voidwellClient) *voidwellDataSource {
+	return &voidwellDataSource{
+		client: client,
+	}
+}
+
+func (s *voidwellDataSource) Name() string {
+	return dataSourceVoidwell
+}
+
+func (s *voidwellDataSource) GetCharacter(characterName string, platform string) (*PlanetsideCharacter, error) {
+	uri := s.client.config.voidwellAPI("ps2/character/byname/%s?platform=%s", characterName, platform)
+	subject := fmt.Sprintf("character named %s on %s", characterName, platformDisplayName(platform))
+
+	resp, stale, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var character PlanetsideCharacter
+	err = decodeVoidwellResponse(uri, resp, &character, subject)
+	if err != nil {
+		return nil, err
+	}
+	character.Platform = platform
+	character.Stale = stale
+
+	return &character, nil
+}
+
+// GetCharacterByID looks up the character's current name and then its stats, since the
+// stats endpoint is keyed by name. This keeps stored IDs working after a rename.
+func (s *voidwellDataSource) GetCharacterByID(characterID string, platform string) (*PlanetsideCharacter, error) {
+	uri := s.client.config.voidwellAPI("ps2/character/%s?platform=%s", characterID, platform)
+	subject := fmt.Sprintf("character with ID %s on %s", characterID, platformDisplayName(platform))
+
+	resp, _, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var details struct {
+		Name string `json:"name"`
+	}
+	err = decodeVoidwellResponse(uri, resp, &details, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if details.Name == "" {
+		return nil, &voidwellError{Kind: voidwellNotFound, Subject: subject}
+	}
+
+	return s.GetCharacter(details.Name, platform)
+}
+
+func (s *voidwellDataSource) GetCharacterWeapon(characterName string, weaponName string, platform string) (*PlanetsideCharacterWeapon, error) {
+	uri := s.client.config.voidwellAPI("ps2/character/byname/%s/weapon/%s?platform=%s", characterName, weaponName, platform)
+	subject := fmt.Sprintf("%s stats for %s on %s", weaponName, characterName, platformDisplayName(platform))
+
+	resp, stale, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var weapon PlanetsideCharacterWeapon
+	err = decodeVoidwellResponse(uri, resp, &weapon, subject)
+	if err != nil {
+		return nil, err
+	}
+	weapon.Stale = stale
+
+	return &weapon, nil
+}
+
+func (s *voidwellDataSource) GetOutfit(outfitAlias string, platform string) (*PlanetsideOutfit, error) {
+	uri := s.client.config.voidwellAPI("ps2/outfit/byalias/%s?platform=%s", outfitAlias, platform)
+	subject := fmt.Sprintf("outfit tagged [%s] on %s", outfitAlias, platformDisplayName(platform))
+
+	resp, stale, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var outfit PlanetsideOutfit
+	err = decodeVoidwellResponse(uri, resp, &outfit, subject)
+	if err != nil {
+		return nil, err
+	}
+	outfit.Stale = stale
+
+	return &outfit, nil
+}
+
+func (s *voidwellDataSource) GetOutfitMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	uri := s.client.config.voidwellAPI("ps2/outfit/%s/members?platform=%s", outfitID, platform)
+	subject := fmt.Sprintf("members for outfit %s on %s", outfitID, platformDisplayName(platform))
+
+	resp, _, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var members []*PlanetsideOutfitMember
+	err = decodeVoidwellResponse(uri, resp, &members, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetOutfitOnlineMembers is not available from Voidwell, which does not expose online status.
+func (s *voidwellDataSource) GetOutfitOnlineMembers(outfitID string, platform string) ([]*PlanetsideOutfitMember, error) {
+	return nil, &voidwellError{Kind: voidwellUnsupported, Subject: "online outfit members"}
+}
+
+func (s *voidwellDataSource) GetWorldStates(platform string) ([]*PlanetsideWorldState, error) {
+	uri := s.client.config.voidwellAPI("ps2/worldstate?platform=%s", platform)
+	subject := fmt.Sprintf("server status for %s", platformDisplayName(platform))
+
+	resp, stale, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var worlds []*PlanetsideWorldState
+	err = decodeVoidwellResponse(uri, resp, &worlds, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, world := range worlds {
+		world.Stale = stale
+	}
+
+	return worlds, nil
+}
+
+func (s *voidwellDataSource) GetWeapon(weaponName string) (*PlanetsideWeapon, error) {
+	uri := s.client.config.voidwellAPI("ps2/weaponinfo/byname/%s", weaponName)
+	subject := fmt.Sprintf("weapon named %s", weaponName)
+
+	resp, stale, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var weapon PlanetsideWeapon
+	err = decodeVoidwellResponse(uri, resp, &weapon, subject)
+	if err != nil {
+		return nil, err
+	}
+	weapon.Stale = stale
+
+	return &weapon, nil
+}
+
+func (s *voidwellDataSource) SearchWeapons(query string) ([]*PlanetsideWeaponSearchResult, error) {
+	uri := s.client.config.voidwellAPI("ps2/search/weapon/%s", url.PathEscape(query))
+	subject := fmt.Sprintf("weapons matching '%s'", query)
+
+	resp, _, err := s.client.get(uri)
+	if err != nil {
+		return nil, describeVoidwellError(err, subject)
+	}
+
+	var results []*PlanetsideWeaponSearchResult
+	err = decodeVoidwellResponse(uri, resp, &results, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ListWeapons is not available from Voidwell, which only searches weapons by name.
+func (s *voidwellDataSource) ListWeapons() ([]*PlanetsideWeaponSearchResult, error) {
+	return nil, &voidwellError{Kind: voidwellUnsupported, Subject: "a list of every weapon"}
+}
+
+// get returns the response for uri, serving it from the cache while fresh.
+// When Voidwell is unavailable, an expired cached response is returned instead and flagged as
+// stale. Misses and rejected requests are returned as errors, so a deleted character is not
+// served from the cache for a day.
+func (c *voidwellClient) get(uri string) (json.RawMessage, bool, error) {
+	if cached, fresh := c.cache.get(uri); fresh {
+		return cached, false, nil
+	}
+
+	result, err, _ := c.requests.Do(uri, func() (interface{}, error) {
+		resp, err := c.fetchWithRetry(uri)
+		if err != nil {
+			if !isUnavailableError(err) {
+				return nil, err
+			}
+
+			if cached, ok := c.cache.getStale(uri); ok {
+				log.Printf("Serving stale response for %v: %v", uri, err)
+				return &voidwellResponse{body: cached, stale: true}, nil
+			}
+			return nil, err
+		}
+
+		c.cache.set(uri, resp)
+
+		return &voidwellResponse{body: resp}, nil
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	response := result.(*voidwellResponse)
+	return response.body, response.stale, nil
+}
+
+// fetchWithRetry retries rate limited and server error responses with jittered exponential
+// backoff, waiting as long as Retry-After asks when it is given.
+func (c *voidwellClient) fetchWithRetry(uri string) (json.RawMessage, error) {
+	backoff := voidwellRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.fetch(uri)
+		if err == nil {
+			return resp, nil
+		}
+
+		voidwellErr, ok := err.(*voidwellError)
+		if !ok || !voidwellErr.retryable() || attempt >= voidwellMaxAttempts {
+			return nil, err
+		}
+
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		if voidwellErr.RetryAfter > 0 {
+			delay = voidwellErr.RetryAfter
+		}
+
+		if delay > voidwellMaxRetryDelay {
+			return nil, err
+		}
+
+		log.Printf("Retrying %v in %s (attempt %d): %d", uri, delay, attempt+1, voidwellErr.StatusCode)
+		time.Sleep(delay)
+
+		backoff *= 2
+	}
+}
+
+func (c *voidwellClient) fetch(uri string) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), voidwellRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+
+	if err != nil {
+		return nil, newVoidwellRequestError(uri, err)
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, newVoidwellRequestError(uri, err)
+	}
+
+	if resp.StatusCode != 200 {
+		voidwellErr := newVoidwellStatusError(uri, resp.StatusCode, body)
+		voidwellErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, voidwellErr
+	}
+
+	if len(body) == 0 {
+		return nil, &voidwellError{Kind: voidwellNotFound}
+	}
+
+	var jsonResponse json.RawMessage
+	err = json.Unmarshal(body, &jsonResponse)
+
+	if err != nil {
+		log.Println(fmt.Sprintf("Failed to unmarshal for %v: %v", uri, err))
+		return nil, &voidwellError{Kind: voidwellMalformed}
+	}
+
+	return jsonResponse, nil
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,200 @@
+package planetsidetwoplugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestVoidwellClient returns a client for a fake Voidwell API answering with handler.
+func newTestVoidwellClient(handler http.HandlerFunc) (*voidwellClient, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	client := &voidwellClient{
+		http:   server.Client(),
+		cache:  newResponseCache(),
+		config: &serviceConfig{VoidwellAPIURI: server.URL + "/"},
+	}
+
+	return client, server
+}
+
+// scriptedResponses answers each request with the next status code, repeating the last one.
+// Successful requests get a small JSON body.
+func scriptedResponses(requests *int32, statusCodes ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(requests, 1)) - 1
+		if i >= len(statusCodes) {
+			i = len(statusCodes) - 1
+		}
+
+		if statusCodes[i] != http.StatusOK {
+			w.WriteHeader(statusCodes[i])
+			return
+		}
+
+		w.Write([]byte(`{"name":"Foo"}`))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value   string
+		minimum time.Duration
+		maximum time.Duration
+	}{
+		{"", 0, 0},
+		{"5", 5 * time.Second, 5 * time.Second},
+		{"0", 0, 0},
+		{"-3", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), 28 * time.Second, 30 * time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+
+	for _, test := range tests {
+		if actual := parseRetryAfter(test.value); actual < test.minimum || actual > test.maximum {
+			t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", test.value, actual, test.minimum, test.maximum)
+		}
+	}
+}
+
+func TestVoidwellFetchWithRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		statusCodes      []int
+		retryAfter       string
+		expectedRequests int32
+		expectedKind     voidwellErrorKind
+		succeeds         bool
+	}{
+		{"success", []int{200}, "", 1, 0, true},
+		{"recovers from a server error", []int{503, 200}, "", 2, 0, true},
+		{"recovers from a rate limit", []int{429, 200}, "1", 2, 0, true},
+		{"gives up after the last attempt", []int{502}, "", voidwellMaxAttempts, voidwellUnavailable, false},
+		{"gives up on a long Retry-After", []int{429}, "60", 1, voidwellRateLimited, false},
+		{"does not retry a miss", []int{404}, "", 1, voidwellNotFound, false},
+		{"does not retry a bad request", []int{400}, "", 1, voidwellRejected, false},
+	}
+
+	for _, test := range tests {
+		var requests int32
+		respond := scriptedResponses(&requests, test.statusCodes...)
+		client, server := newTestVoidwellClient(func(w http.ResponseWriter, r *http.Request) {
+			if test.retryAfter != "" {
+				w.Header().Set("Retry-After", test.retryAfter)
+			}
+			respond(w, r)
+		})
+
+		_, err := client.fetchWithRetry(client.config.voidwellAPI("ps2/character/byname/Foo"))
+		server.Close()
+
+		if requests != test.expectedRequests {
+			t.Errorf("%s: made %d requests, want %d", test.name, requests, test.expectedRequests)
+		}
+
+		if test.succeeds {
+			if err != nil {
+				t.Errorf("%s: returned error %v", test.name, err)
+			}
+			continue
+		}
+
+		voidwellErr, ok := err.(*voidwellError)
+		if !ok || voidwellErr.Kind != test.expectedKind {
+			t.Errorf("%s: returned error %#v, want kind %d", test.name, err, test.expectedKind)
+		}
+	}
+}
+
+func TestVoidwellGetCoalescesRequests(t *testing.T) {
+	var requests int32
+	arrived := make(chan bool, 1)
+	release := make(chan bool)
+
+	client, server := newTestVoidwellClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		arrived <- true
+		<-release
+		w.Write([]byte(`{"name":"Foo"}`))
+	})
+	defer server.Close()
+
+	uri := client.config.voidwellAPI("ps2/character/byname/Foo")
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = client.get(uri)
+		}(i)
+	}
+
+	<-arrived
+	// Give the other callers time to join the request in flight.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("caller %d returned error %v", i, err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("made %d requests for %d concurrent callers, want 1", requests, callers)
+	}
+
+	// The response is now cached.
+	if _, _, err := client.get(uri); err != nil || requests != 1 {
+		t.Errorf("cached get made %d requests and returned %v, want 1 request and no error", requests, err)
+	}
+}
+
+func TestVoidwellGetServesStaleResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		expectStale bool
+	}{
+		{"unavailable", http.StatusServiceUnavailable, true},
+		{"unauthorized", http.StatusUnauthorized, true},
+		{"not found", http.StatusNotFound, false},
+		{"bad request", http.StatusBadRequest, false},
+	}
+
+	for _, test := range tests {
+		var requests int32
+		client, server := newTestVoidwellClient(scriptedResponses(&requests, http.StatusOK, test.statusCode))
+		uri := client.config.voidwellAPI("ps2/character/byname/Foo")
+
+		if _, _, err := client.get(uri); err != nil {
+			t.Fatalf("%s: first get returned error %v", test.name, err)
+		}
+
+		client.cache.entries[uri].expires = time.Now().Add(-time.Minute)
+
+		// Server errors are retried, so this can take a couple of seconds.
+		body, stale, err := client.get(uri)
+		server.Close()
+
+		if test.expectStale {
+			if err != nil || !stale || string(body) != `{"name":"Foo"}` {
+				t.Errorf("%s: get = %s, %t, %v, want the stale response", test.name, body, stale, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("%s: get = %s, %t, want an error", test.name, body, stale)
+		}
+	}
+}
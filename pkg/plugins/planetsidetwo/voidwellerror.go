@@ -0,0 +1,151 @@
+package planetsidetwoplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type voidwellErrorKind int
+
+const (
+	voidwellNotFound voidwellErrorKind = iota
+	voidwellUnauthorized
+	voidwellRateLimited
+	voidwellUnavailable
+	voidwellTimeout
+	voidwellMalformed
+	voidwellRejected
+	voidwellUnsupported
+)
+
+// voidwellError describes a failed Voidwell call. Its message is meant for the channel, so
+// the response body and underlying error are only logged.
+type voidwellError struct {
+	Kind       voidwellErrorKind
+	StatusCode int
+	// Subject names what was looked up, e.g. "character named Foo on PS4 EU", or for
+	// voidwellUnsupported, what Voidwell does not provide.
+	Subject string
+	// RetryAfter is how long Voidwell asked to wait before trying again, if it said.
+	RetryAfter time.Duration
+}
+
+func (e *voidwellError) Error() string {
+	switch e.Kind {
+	case voidwellNotFound:
+		if e.Subject != "" {
+			return fmt.Sprintf("No %s.", e.Subject)
+		}
+		return "Nothing was found."
+	case voidwellUnauthorized:
+		return "The bot could not sign in to Voidwell. Let the bot owner know."
+	case voidwellRateLimited:
+		return "Voidwell is getting too many requests right now. Try again in a minute."
+	case voidwellTimeout:
+		return "Voidwell took too long to respond. Try again later."
+	case voidwellMalformed:
+		return "Voidwell sent a response the bot could not read."
+	case voidwellRejected:
+		if e.Subject != "" {
+			return fmt.Sprintf("Voidwell could not look up the %s.", e.Subject)
+		}
+		return "Voidwell could not answer that request."
+	case voidwellUnsupported:
+		return fmt.Sprintf("Voidwell does not provide %s.", e.Subject)
+	}
+
+	return "Voidwell is having trouble right now. Try again later."
+}
+
+// retryable reports whether the request may succeed if tried again shortly.
+func (e *voidwellError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// unavailable reports whether the failure was Voidwell's rather than the lookup's, so another
+// data source may be able to answer it. That includes lookups Voidwell does not support.
+func (e *voidwellError) unavailable() bool {
+	return e.Kind != voidwellNotFound && e.Kind != voidwellRejected
+}
+
+// describeVoidwellError names the subject of a failed lookup, so a miss reads as "No character named Foo on PC".
+func describeVoidwellError(err error, subject string) error {
+	if voidwellErr, ok := err.(*voidwellError); ok {
+		described := *voidwellErr
+		described.Subject = subject
+		return &described
+	}
+
+	return err
+}
+
+// newVoidwellStatusError classifies an unsuccessful response and logs its body.
+func newVoidwellStatusError(uri string, statusCode int, body []byte) *voidwellError {
+	log.Printf("Voidwell returned %d for %v: %s", statusCode, uri, body)
+
+	kind := voidwellUnavailable
+	switch {
+	case statusCode == http.StatusNotFound || statusCode == http.StatusNoContent:
+		kind = voidwellNotFound
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = voidwellUnauthorized
+	case statusCode == http.StatusTooManyRequests:
+		kind = voidwellRateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		kind = voidwellTimeout
+	case statusCode >= 400 && statusCode < 500:
+		kind = voidwellRejected
+	}
+
+	return &voidwellError{Kind: kind, StatusCode: statusCode}
+}
+
+// newVoidwellRequestError classifies a request that got no response, such as a timeout or a
+// failure to get an access token, and logs the underlying error.
+func newVoidwellRequestError(uri string, err error) *voidwellError {
+	log.Printf("Voidwell request for %v failed: %v", uri, err)
+
+	if err == context.DeadlineExceeded {
+		return &voidwellError{Kind: voidwellTimeout}
+	}
+
+	if urlErr, ok := err.(*url.Error); ok {
+		if _, ok := urlErr.Err.(*oauth2.RetrieveError); ok {
+			return &voidwellError{Kind: voidwellUnauthorized}
+		}
+
+		if urlErr.Err == context.DeadlineExceeded {
+			return &voidwellError{Kind: voidwellTimeout}
+		}
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return &voidwellError{Kind: voidwellTimeout}
+	}
+
+	return &voidwellError{Kind: voidwellUnavailable}
+}
+
+// decodeVoidwellResponse unmarshals a Voidwell response into result. An empty or null body
+// means Voidwell knew nothing about the subject.
+func decodeVoidwellResponse(uri string, resp json.RawMessage, result interface{}, subject string) error {
+	if len(resp) == 0 || string(resp) == "null" {
+		return &voidwellError{Kind: voidwellNotFound, Subject: subject}
+	}
+
+	err := json.Unmarshal(resp, result)
+	if err != nil {
+		log.Printf("Failed to unmarshal Voidwell response for %v: %v", uri, err)
+		return &voidwellError{Kind: voidwellMalformed, Subject: subject}
+	}
+
+	return nil
+}
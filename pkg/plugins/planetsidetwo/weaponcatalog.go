@@ -0,0 +1,258 @@
+package planetsidetwoplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWeaponCatalogInterval = 24 * time.Hour
+	// weaponCatalogRequestDelay spaces out lookups during a catalog sync.
+	weaponCatalogRequestDelay = 2 * time.Second
+)
+
+// weaponStatChange is one stat that differs between the cataloged weapon and the synced one.
+type weaponStatChange struct {
+	Stat     string
+	OldValue string
+	NewValue string
+}
+
+// weaponChange lists the stats of a weapon that changed during a catalog sync.
+type weaponChange struct {
+	Weapon  *PlanetsideWeapon
+	Changes []weaponStatChange
+}
+
+// weaponCatalogStats are the stats compared between syncs, in the order changes are reported.
+var weaponCatalogStats = []struct {
+	label string
+	value func(weapon *PlanetsideWeapon) string
+}{
+	{"Max damage", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%d @ %dm", w.MaxDamage, w.MaxDamageRange) }},
+	{"Min damage", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%d @ %dm", w.MinDamage, w.MinDamageRange) }},
+	{"Indirect damage", func(w *PlanetsideWeapon) string {
+		return fmt.Sprintf("%d-%d @ %0.1f-%0.1fm", w.IndirectMaxDamage, w.IndirectMinDamage, w.IndirectMaxDamageRange, w.IndirectMinDamageRange)
+	}},
+	{"Fire rate", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%d ms", w.FireRateMs) }},
+	{"Magazine", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%d", w.ClipSize) }},
+	{"Ammo capacity", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%d", w.Capacity) }},
+	{"Muzzle velocity", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%d m/s", w.MuzzleVelocity) }},
+	{"Reload", func(w *PlanetsideWeapon) string {
+		return fmt.Sprintf("%0.3fs / %0.3fs", float32(w.MinReloadSpeed)/1000, float32(w.MaxReloadSpeed)/1000)
+	}},
+	{"Fire modes", func(w *PlanetsideWeapon) string { return strings.Join(w.FireModes, ", ") }},
+	{"Iron sight zoom", func(w *PlanetsideWeapon) string { return fmt.Sprintf("%0.2fx", w.IronSightZoom) }},
+	{"Hip accuracy", func(w *PlanetsideWeapon) string { return formatCatalogAccuracy(w.HipAcc) }},
+	{"ADS accuracy", func(w *PlanetsideWeapon) string { return formatCatalogAccuracy(w.AimAcc) }},
+}
+
+func formatCatalogAccuracy(state *PlanetsideWeaponAccuracyState) string {
+	if state == nil {
+		return "None"
+	}
+
+	return fmt.Sprintf("%0.2f/%0.2f/%0.2f/%0.2f (+%0.2f)", state.Crouching, state.CrouchWalking, state.Standing, state.Running, state.Cof)
+}
+
+func weaponCatalogInterval() time.Duration {
+	if value := os.Getenv("PS2WeaponCatalogInterval"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Invalid PS2WeaponCatalogInterval '%s', using %s", value, defaultWeaponCatalogInterval)
+	}
+
+	return defaultWeaponCatalogInterval
+}
+
+// getWeapon serves the weapon from the catalog, only asking the data source about weapons that
+// have not been synced yet. Those are added so later lookups stay local.
+func (p *planetsidetwoPlugin) getWeapon(weaponName string) (*PlanetsideWeapon, error) {
+	weapon, err := p.repository.getCatalogWeapon(weaponName)
+	if err != nil {
+		log.Printf("Failed to get cataloged weapon '%s': %s", weaponName, err)
+	}
+
+	if weapon != nil {
+		return weapon, nil
+	}
+
+	weapon, err = p.dataSource.GetWeapon(weaponName)
+	if err != nil {
+		return nil, err
+	}
+
+	if weapon.Name != "" && !weapon.Stale {
+		err = p.repository.upsertCatalogWeapon(weapon)
+		if err != nil {
+			log.Printf("Failed to catalog weapon '%s': %s", weapon.Name, err)
+		}
+	}
+
+	return weapon, nil
+}
+
+// runWeaponCatalogJob seeds an empty catalog and then syncs it on each interval tick, posting
+// any balance changes to the channels subscribed to patch notes. A freshly seeded catalog is
+// synced straight away so the seeded weapons get their stats as soon as the data source answers.
+func (p *planetsidetwoPlugin) runWeaponCatalogJob(interval time.Duration) {
+	if p.seedWeaponCatalog() {
+		p.syncWeaponCatalog()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.sendPatchNotes(p.syncWeaponCatalog())
+	}
+}
+
+// seedWeaponCatalog fills an empty catalog with the bundled weapon names, reporting whether it did.
+func (p *planetsidetwoPlugin) seedWeaponCatalog() bool {
+	count, err := p.repository.countCatalogWeapons()
+	if err != nil {
+		log.Printf("Failed to count cataloged weapons: %s", err)
+		return false
+	}
+
+	if count > 0 {
+		return false
+	}
+
+	var weapons []*PlanetsideWeapon
+	err = json.Unmarshal([]byte(defaultWeaponCatalog), &weapons)
+	if err != nil {
+		log.Printf("Failed to read bundled weapon catalog: %s", err)
+		return false
+	}
+
+	for _, weapon := range weapons {
+		err = p.repository.seedCatalogWeapon(weapon)
+		if err != nil {
+			log.Printf("Failed to seed weapon '%s': %s", weapon.Name, err)
+		}
+	}
+
+	log.Printf("Seeded weapon catalog with %d weapons", len(weapons))
+
+	return true
+}
+
+// syncWeaponCatalog refreshes every cataloged weapon from the data source, adding the weapons
+// it lists that are not cataloged yet, and returns the weapons whose stats changed. Each change
+// is logged. New and seeded weapons have no stats to compare, so their first sync is not
+// reported as a balance change.
+func (p *planetsidetwoPlugin) syncWeaponCatalog() []*weaponChange {
+	names, err := p.repository.getCatalogWeaponNames()
+	if err != nil {
+		log.Printf("Failed to get cataloged weapons: %s", err)
+		return nil
+	}
+
+	names = append(names, p.uncatalogedWeaponNames(names)...)
+
+	changes := make([]*weaponChange, 0)
+	synced := 0
+
+	for _, name := range names {
+		time.Sleep(weaponCatalogRequestDelay)
+
+		weapon, err := p.dataSource.GetWeapon(name)
+		if err != nil {
+			log.Printf("Failed to sync weapon '%s': %s", name, err)
+			continue
+		}
+
+		if weapon.Stale || weapon.Name == "" {
+			continue
+		}
+
+		previous, err := p.repository.getCatalogWeapon(name)
+		if err != nil {
+			log.Printf("Failed to get cataloged weapon '%s': %s", name, err)
+		}
+
+		err = p.repository.upsertCatalogWeapon(weapon)
+		if err != nil {
+			log.Printf("Failed to catalog weapon '%s': %s", weapon.Name, err)
+			continue
+		}
+
+		synced++
+
+		if previous == nil {
+			continue
+		}
+
+		if change := diffWeapon(previous, weapon); change != nil {
+			for _, stat := range change.Changes {
+				log.Printf("Weapon catalog: %s %s changed from %s to %s", weapon.Name, strings.ToLower(stat.Stat), stat.OldValue, stat.NewValue)
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	log.Printf("Synced %d of %d cataloged weapons, %d changed", synced, len(names), len(changes))
+
+	return changes
+}
+
+// uncatalogedWeaponNames returns the names of the data source's weapons that are missing from the
+// catalog, so the sync covers every weapon rather than only those someone looked up.
+func (p *planetsidetwoPlugin) uncatalogedWeaponNames(cataloged []string) []string {
+	weapons, err := p.dataSource.ListWeapons()
+	if err != nil {
+		log.Printf("Failed to list weapons: %s", err)
+		return nil
+	}
+
+	known := make(map[string]bool, len(cataloged))
+	for _, name := range cataloged {
+		known[strings.ToLower(name)] = true
+	}
+
+	names := make([]string, 0)
+	for _, weapon := range weapons {
+		key := strings.ToLower(weapon.Name)
+		if weapon.Name == "" || known[key] {
+			continue
+		}
+
+		known[key] = true
+		names = append(names, weapon.Name)
+	}
+
+	return names
+}
+
+// diffWeapon compares the stats of two versions of a weapon, returning nil when nothing changed.
+func diffWeapon(previous *PlanetsideWeapon, current *PlanetsideWeapon) *weaponChange {
+	statChanges := make([]weaponStatChange, 0)
+
+	for _, stat := range weaponCatalogStats {
+		oldValue, newValue := stat.value(previous), stat.value(current)
+		if oldValue != newValue {
+			statChanges = append(statChanges, weaponStatChange{
+				Stat:     stat.label,
+				OldValue: oldValue,
+				NewValue: newValue,
+			})
+		}
+	}
+
+	if len(statChanges) == 0 {
+		return nil
+	}
+
+	return &weaponChange{
+		Weapon:  current,
+		Changes: statChanges,
+	}
+}
@@ -0,0 +1,14 @@
+package planetsidetwoplugin
+
+// defaultWeaponCatalog names a few of the most asked about weapons so an empty catalog can offer
+// them as search candidates before the first sync. It carries no stats: seeded entries are never
+// served or diffed, and the sync that follows seeding replaces them with the data source's stats.
+const defaultWeaponCatalog = `[
+	{ "name": "NS-11A" },
+	{ "name": "Gauss SAW" },
+	{ "name": "T9 CARV" },
+	{ "name": "Orion VS54" },
+	{ "name": "Gauss Rifle" },
+	{ "name": "Cycler TRV" },
+	{ "name": "Pulsar C" }
+]`
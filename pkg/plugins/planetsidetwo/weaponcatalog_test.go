@@ -0,0 +1,142 @@
+package planetsidetwoplugin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func gaussSAW() *PlanetsideWeapon {
+	return &PlanetsideWeapon{
+		Name:           "Gauss SAW",
+		Category:       "LMG",
+		Description:    "Before the patch",
+		FireRateMs:     120,
+		ClipSize:       50,
+		Capacity:       250,
+		MuzzleVelocity: 600,
+		MaxDamage:      200,
+		MaxDamageRange: 10,
+		MinDamage:      167,
+		MinDamageRange: 65,
+		MinReloadSpeed: 2600,
+		MaxReloadSpeed: 3400,
+		FireModes:      []string{"Auto"},
+		HipAcc:         &PlanetsideWeaponAccuracyState{Crouching: 2, CrouchWalking: 2.5, Standing: 3, Running: 3.5, Cof: 0.1},
+	}
+}
+
+func TestDiffWeapon(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(weapon *PlanetsideWeapon)
+		expected []weaponStatChange
+	}{
+		{"unchanged", func(w *PlanetsideWeapon) {}, nil},
+		{
+			"untracked fields",
+			func(w *PlanetsideWeapon) {
+				w.Description = "After the patch"
+				w.ImageID = 1234
+				w.Stale = true
+			},
+			nil,
+		},
+		{
+			"damage",
+			func(w *PlanetsideWeapon) { w.MinDamage = 143 },
+			[]weaponStatChange{{"Min damage", "167 @ 65m", "143 @ 65m"}},
+		},
+		{
+			"damage range",
+			func(w *PlanetsideWeapon) { w.MaxDamageRange = 20 },
+			[]weaponStatChange{{"Max damage", "200 @ 10m", "200 @ 20m"}},
+		},
+		{
+			"several stats, in report order",
+			func(w *PlanetsideWeapon) {
+				w.MaxReloadSpeed = 3000
+				w.FireRateMs = 109
+				w.FireModes = []string{"Auto", "Burst"}
+			},
+			[]weaponStatChange{
+				{"Fire rate", "120 ms", "109 ms"},
+				{"Reload", "2.600s / 3.400s", "2.600s / 3.000s"},
+				{"Fire modes", "Auto", "Auto, Burst"},
+			},
+		},
+		{
+			"accuracy removed",
+			func(w *PlanetsideWeapon) { w.HipAcc = nil },
+			[]weaponStatChange{{"Hip accuracy", "2.00/2.50/3.00/3.50 (+0.10)", "None"}},
+		},
+		{
+			"accuracy added",
+			func(w *PlanetsideWeapon) { w.AimAcc = &PlanetsideWeaponAccuracyState{Standing: 0.5} },
+			[]weaponStatChange{{"ADS accuracy", "None", "0.00/0.00/0.50/0.00 (+0.00)"}},
+		},
+	}
+
+	for _, test := range tests {
+		previous, current := gaussSAW(), gaussSAW()
+		test.change(current)
+
+		change := diffWeapon(previous, current)
+
+		if test.expected == nil {
+			if change != nil {
+				t.Errorf("%s: diffWeapon reported %+v, want no change", test.name, change.Changes)
+			}
+			continue
+		}
+
+		if change == nil {
+			t.Errorf("%s: diffWeapon reported no change, want %+v", test.name, test.expected)
+			continue
+		}
+
+		if change.Weapon != current {
+			t.Errorf("%s: diffWeapon reported the previous weapon, want the current one", test.name)
+		}
+
+		if len(change.Changes) != len(test.expected) {
+			t.Errorf("%s: diffWeapon reported %+v, want %+v", test.name, change.Changes, test.expected)
+			continue
+		}
+
+		for i, expected := range test.expected {
+			if change.Changes[i] != expected {
+				t.Errorf("%s: diffWeapon reported %+v, want %+v", test.name, change.Changes[i], expected)
+			}
+		}
+	}
+}
+
+func TestUncatalogedWeaponNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		cataloged []string
+		listed    []string
+		err       error
+		expected  []string
+	}{
+		{"new weapons", []string{"NS-11A"}, []string{"NS-11A", "Gauss SAW", "Orion VS54"}, nil, []string{"Gauss SAW", "Orion VS54"}},
+		{"names ignore case", []string{"gauss saw"}, []string{"Gauss SAW"}, nil, []string{}},
+		{"duplicates and blanks", nil, []string{"Gauss SAW", "", "GAUSS SAW"}, nil, []string{"Gauss SAW"}},
+		{"list fails", []string{"NS-11A"}, nil, errors.New("Census is having trouble right now."), nil},
+	}
+
+	for _, test := range tests {
+		listed := make([]*PlanetsideWeaponSearchResult, 0, len(test.listed))
+		for _, name := range test.listed {
+			listed = append(listed, &PlanetsideWeaponSearchResult{Name: name})
+		}
+
+		plugin := &planetsidetwoPlugin{dataSource: &fakeDataSource{weapons: listed, err: test.err}}
+
+		actual := plugin.uncatalogedWeaponNames(test.cataloged)
+		if strings.Join(actual, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%s: uncatalogedWeaponNames returned %v, want %v", test.name, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+func (p *planetsidetwoPlugin) runWeaponCompareCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	names := strings.Split(args["weaponNames"], "|")
+	if len(names) != 2 || strings.TrimSpace(names[0]) == "" || strings.TrimSpace(names[1]) == "" {
+		p.RLock()
+		client.SendMessage(message.Channel(), "Please provide two weapons separated by '|'.")
+		p.RUnlock()
+		return
+	}
+
+	weapons := make([]*PlanetsideWeapon, len(names))
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			weapons[i], errs[i] = p.getWeapon(name)
+		}(i, strings.TrimSpace(name))
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			p.RLock()
+			client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+			p.RUnlock()
+			return
+		}
+	}
+
+	infantry := targetProfiles[defaultTargetProfile]
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(weapons))
+	for _, weapon := range weapons {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   weapon.Name,
+			Value:  strings.Join(weaponComparisonLines(weapon, infantry), "\n"),
+			Inline: true,
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: weapons[0].Name + " vs " + weapons[1].Name,
+		},
+		Color:       p.embedColor(client, message.Channel(), 0),
+		Description: fmt.Sprintf("Time to kill is against %s (%0.f health and shields) without headshots.", strings.ToLower(infantry.Description), infantry.totalHealth()),
+		Fields:      fields,
+		Footer:      createStaleFooter(weapons[0].Stale || weapons[1].Stale),
+	}
+
+	chart, legend, err := renderDamageChart(weapons)
+	if err != nil {
+		log.Printf("Failed to render damage chart for '%s': %s", embed.Author.Name, err)
+	}
+
+	if chart != nil {
+		embed.Description += "\n\nChart: " + strings.Join(legend, ", ")
+	}
+
+	p.sendEmbedWithChart(client, message.Channel(), embed, chart)
+}
+
+func weaponComparisonLines(weapon *PlanetsideWeapon, target *targetProfile) []string {
+	lines := []string{
+		fmt.Sprintf("Type: %s", weapon.Category),
+	}
+
+	if weapon.FireRateMs > 0 {
+		lines = append(lines, fmt.Sprintf("Fire rate: %d RPM", 60000/weapon.FireRateMs))
+	} else {
+		lines = append(lines, "Fire rate: n/a")
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("Damage: %d @ %dm / %d @ %dm", weapon.MaxDamage, weapon.MaxDamageRange, weapon.MinDamage, weapon.MinDamageRange),
+		fmt.Sprintf("Reload: %0.2fs / %0.2fs", float32(weapon.MinReloadSpeed)/1000, float32(weapon.MaxReloadSpeed)/1000),
+		fmt.Sprintf("Magazine: %d / %d", weapon.ClipSize, weapon.Capacity),
+	)
+
+	if weapon.HipAcc != nil {
+		lines = append(lines, fmt.Sprintf("Hip: %s", formatAccuracyState(weapon.HipAcc)))
+	}
+
+	if weapon.AimAcc != nil {
+		lines = append(lines, fmt.Sprintf("ADS: %s", formatAccuracyState(weapon.AimAcc)))
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("TTK ≤%dm: %s", weapon.MaxDamageRange, formatTimeToKill(target.effectiveDamage(float64(weapon.MaxDamage), 0, 1), target.totalHealth(), weapon.FireRateMs)),
+		fmt.Sprintf("TTK ≥%dm: %s", weapon.MinDamageRange, formatTimeToKill(target.effectiveDamage(float64(weapon.MinDamage), 0, 1), target.totalHealth(), weapon.FireRateMs)),
+	)
+
+	return lines
+}
+
+func formatAccuracyState(state *PlanetsideWeaponAccuracyState) string {
+	return fmt.Sprintf("%0.2f / %0.2f / %0.2f / %0.2f / %0.2f", state.Crouching, state.CrouchWalking, state.Standing, state.Running, state.Cof)
+}
@@ -0,0 +1,64 @@
+package planetsidetwoplugin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWeaponComparisonLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		weapon   *PlanetsideWeapon
+		expected []string
+	}{
+		{
+			"automatic",
+			&PlanetsideWeapon{Category: "LMG", FireRateMs: 75, MaxDamage: 143, MaxDamageRange: 10, MinDamage: 125, MinDamageRange: 65, MinReloadSpeed: 2500, MaxReloadSpeed: 3300, ClipSize: 100, Capacity: 500},
+			[]string{
+				"Type: LMG",
+				"Fire rate: 800 RPM",
+				"Damage: 143 @ 10m / 125 @ 65m",
+				"Reload: 2.50s / 3.30s",
+				"Magazine: 100 / 500",
+				"TTK ≤10m: 7 shots / 450 ms",
+				"TTK ≥65m: 8 shots / 525 ms",
+			},
+		},
+		{
+			"no fire rate",
+			&PlanetsideWeapon{Category: "Rocket Launcher", MaxDamage: 1000, MinDamage: 0, ClipSize: 1, Capacity: 6},
+			[]string{
+				"Type: Rocket Launcher",
+				"Fire rate: n/a",
+				"Damage: 1000 @ 0m / 0 @ 0m",
+				"Reload: 0.00s / 0.00s",
+				"Magazine: 1 / 6",
+				"TTK ≤0m: 1 shots",
+				"TTK ≥0m: n/a",
+			},
+		},
+		{
+			"accuracy",
+			&PlanetsideWeapon{Category: "Carbine", FireRateMs: 100, MaxDamage: 167, MinDamage: 143, HipAcc: &PlanetsideWeaponAccuracyState{Crouching: 1, CrouchWalking: 1.5, Standing: 2, Running: 2.5, Cof: 0.1}},
+			[]string{
+				"Type: Carbine",
+				"Fire rate: 600 RPM",
+				"Damage: 167 @ 0m / 143 @ 0m",
+				"Reload: 0.00s / 0.00s",
+				"Magazine: 0 / 0",
+				"Hip: 1.00 / 1.50 / 2.00 / 2.50 / 0.10",
+				"TTK ≤0m: 6 shots / 500 ms",
+				"TTK ≥0m: 7 shots / 600 ms",
+			},
+		},
+	}
+
+	infantry := targetProfiles[defaultTargetProfile]
+
+	for _, test := range tests {
+		actual := weaponComparisonLines(test.weapon, infantry)
+		if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%s: weaponComparisonLines returned\n%s\nwant\n%s", test.name, strings.Join(actual, "\n"), strings.Join(test.expected, "\n"))
+		}
+	}
+}
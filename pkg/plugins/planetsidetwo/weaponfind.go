@@ -0,0 +1,376 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lampjaw/discordgobot"
+)
+
+const (
+	weaponFindPageSize      = 15
+	weaponFindNameWidth     = 22
+	weaponFindDefaultSortBy = "name"
+)
+
+var weaponFilterPattern = regexp.MustCompile(`^([a-zA-Z]+)(>=|<=|!=|=|>|<)(.+)$`)
+
+// weaponCategoryAliases maps the shorthand players use to catalog categories.
+var weaponCategoryAliases = map[string]string{
+	"heavy":   "heavy weapon",
+	"ar":      "assault rifle",
+	"smg":     "smg",
+	"br":      "battle rifle",
+	"sniper":  "sniper rifle",
+	"sr":      "scout rifle",
+	"shotgun": "shotgun",
+	"pistol":  "pistol",
+}
+
+// weaponFindStat is a numeric weapon stat that can be filtered and sorted on.
+type weaponFindStat struct {
+	value func(weapon *PlanetsideWeapon) float64
+	// ascending stats are better when lower, so they sort lowest first.
+	ascending bool
+}
+
+var weaponFindStats = map[string]weaponFindStat{
+	"rpm":       {value: weaponRPM},
+	"mag":       {value: func(w *PlanetsideWeapon) float64 { return float64(w.ClipSize) }},
+	"ammo":      {value: func(w *PlanetsideWeapon) float64 { return float64(w.Capacity) }},
+	"damage":    {value: func(w *PlanetsideWeapon) float64 { return float64(w.MaxDamage) }},
+	"mindamage": {value: func(w *PlanetsideWeapon) float64 { return float64(w.MinDamage) }},
+	"velocity":  {value: func(w *PlanetsideWeapon) float64 { return float64(w.MuzzleVelocity) }},
+	"reload":    {value: func(w *PlanetsideWeapon) float64 { return float64(w.MinReloadSpeed) / 1000 }, ascending: true},
+	"ttk":       {value: weaponTTK, ascending: true},
+}
+
+type weaponFilter struct {
+	field    string
+	operator string
+	value    string
+}
+
+type weaponFindOptions struct {
+	filters []weaponFilter
+	sortBy  string
+	page    int
+}
+
+// parseWeaponFindOptions parses filters separated by whitespace. Values with spaces, like
+// category="Assault Rifle", are quoted or written with underscores instead.
+func parseWeaponFindOptions(text string) (*weaponFindOptions, error) {
+	options := &weaponFindOptions{
+		sortBy: weaponFindDefaultSortBy,
+		page:   1,
+	}
+
+	tokens, err := splitWeaponFindTokens(text)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		match := weaponFilterPattern.FindStringSubmatch(token)
+		if match == nil {
+			return nil, fmt.Errorf("Invalid filter '%s'. Use filters like category=LMG or rpm>700.", token)
+		}
+
+		filter := weaponFilter{
+			field:    strings.ToLower(match[1]),
+			operator: match[2],
+			value:    strings.TrimSpace(strings.Replace(match[3], "_", " ", -1)),
+		}
+
+		switch filter.field {
+		case "sort":
+			sortBy := strings.ToLower(filter.value)
+			if _, ok := weaponFindStats[sortBy]; !ok && sortBy != "name" {
+				return nil, fmt.Errorf("Unknown sort '%s'. Use name, %s.", filter.value, strings.Join(weaponFindStatNames(), ", "))
+			}
+			options.sortBy = sortBy
+			continue
+		case "page":
+			page, err := strconv.Atoi(filter.value)
+			if err != nil || page < 1 {
+				return nil, fmt.Errorf("Invalid page '%s'.", filter.value)
+			}
+			options.page = page
+			continue
+		case "category", "faction", "vehicle":
+			if filter.operator != "=" && filter.operator != "!=" {
+				return nil, fmt.Errorf("%s can only be compared with = or !=.", filter.field)
+			}
+			if _, ok := findFactionID(filter.value); filter.field == "faction" && !ok {
+				return nil, fmt.Errorf("Unknown faction '%s'.", filter.value)
+			}
+			if _, ok := parseYesNo(filter.value); filter.field == "vehicle" && !ok {
+				return nil, fmt.Errorf("Invalid vehicle value '%s'. Use yes or no.", filter.value)
+			}
+		default:
+			if _, ok := weaponFindStats[filter.field]; !ok {
+				return nil, fmt.Errorf("Unknown filter '%s'. Use category, faction, vehicle, %s.", filter.field, strings.Join(weaponFindStatNames(), ", "))
+			}
+			if _, err := strconv.ParseFloat(filter.value, 64); err != nil {
+				return nil, fmt.Errorf("Invalid number '%s' for %s.", filter.value, filter.field)
+			}
+		}
+
+		options.filters = append(options.filters, filter)
+	}
+
+	return options, nil
+}
+
+// splitWeaponFindTokens splits text on whitespace outside of double quotes, dropping the quotes.
+// Discord clients may send curly quotes, so those count too.
+func splitWeaponFindTokens(text string) ([]string, error) {
+	tokens := make([]string, 0)
+	var token strings.Builder
+	quoted, inToken := false, false
+
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted = !quoted
+			inToken = true
+		case unicode.IsSpace(r) && !quoted:
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("Missing closing quote in '%s'.", strings.TrimSpace(text))
+	}
+
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens, nil
+}
+
+func weaponFindStatNames() []string {
+	names := make([]string, 0, len(weaponFindStats))
+	for name := range weaponFindStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// matches reports whether the weapon passes the filter. Faction values are matched against
+// faction codes and names, with 'NS' or 'none' meaning weapons every faction can use.
+func (f weaponFilter) matches(weapon *PlanetsideWeapon) bool {
+	var equal bool
+
+	switch f.field {
+	case "category":
+		category := strings.ToLower(f.value)
+		if alias, ok := weaponCategoryAliases[category]; ok {
+			category = alias
+		}
+		equal = strings.EqualFold(weapon.Category, category)
+	case "faction":
+		factionID, _ := findFactionID(f.value)
+		equal = weapon.FactionID == factionID
+	case "vehicle":
+		vehicle, _ := parseYesNo(f.value)
+		equal = weapon.IsVehicleWeapon == vehicle
+	default:
+		expected, _ := strconv.ParseFloat(f.value, 64)
+		actual := weaponFindStats[f.field].value(weapon)
+
+		switch f.operator {
+		case ">":
+			return actual > expected
+		case ">=":
+			return actual >= expected
+		case "<":
+			return actual < expected
+		case "<=":
+			return actual <= expected
+		}
+		equal = actual == expected
+	}
+
+	if f.operator == "!=" {
+		return !equal
+	}
+
+	return equal
+}
+
+func findFactionID(value string) (int, bool) {
+	switch strings.ToLower(value) {
+	case "ns", "none", "common":
+		return 0, true
+	}
+
+	for _, factionID := range planetsideMetadata.factionIDs() {
+		if strings.EqualFold(getFactionCode(factionID), value) || strings.EqualFold(getFactionName(factionID), value) {
+			return factionID, true
+		}
+	}
+
+	return 0, false
+}
+
+func parseYesNo(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "true":
+		return true, true
+	case "no", "n", "false":
+		return false, true
+	}
+
+	return false, false
+}
+
+func weaponRPM(weapon *PlanetsideWeapon) float64 {
+	if weapon.FireRateMs <= 0 {
+		return 0
+	}
+
+	return 60000 / float64(weapon.FireRateMs)
+}
+
+// weaponTTK is the point blank, body shot time to kill against the default target. Weapons
+// without a fire rate cannot be ranked, so they sort last.
+func weaponTTK(weapon *PlanetsideWeapon) float64 {
+	target := targetProfiles[defaultTargetProfile]
+	shots := shotsToKill(damageAtRange(weapon, 0), target.totalHealth())
+
+	if shots == 0 || weapon.FireRateMs <= 0 {
+		return math.MaxInt32
+	}
+
+	return float64(timeToKill(shots, weapon.FireRateMs))
+}
+
+func (p *planetsidetwoPlugin) runWeaponFindCommand(bot *discordgobot.Gobot, client *discordgobot.DiscordClient, payload discordgobot.CommandPayload) {
+	args, message := payload.Arguments, payload.Message
+
+	options, err := parseWeaponFindOptions(args["filters"])
+	if err != nil {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("%s", err))
+		p.RUnlock()
+		return
+	}
+
+	weapons, err := p.repository.getCatalogWeapons()
+	if err != nil {
+		log.Printf("Failed to get cataloged weapons: %s", err)
+		p.RLock()
+		client.SendMessage(message.Channel(), "Failed to search the weapon catalog.")
+		p.RUnlock()
+		return
+	}
+
+	results := make([]*PlanetsideWeapon, 0)
+	for _, weapon := range weapons {
+		matched := true
+		for _, filter := range options.filters {
+			if !filter.matches(weapon) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			results = append(results, weapon)
+		}
+	}
+
+	if len(results) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("No weapons out of %d cataloged match.", len(weapons)))
+		p.RUnlock()
+		return
+	}
+
+	sortWeapons(results, options.sortBy)
+
+	lines := make([]string, len(results))
+	for i, weapon := range results {
+		lines[i] = formatWeaponFindRow(weapon)
+	}
+
+	title := fmt.Sprintf("%d matching weapons", len(results))
+	footer := &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Searched %d cataloged weapons, sorted by %s", len(weapons), options.sortBy),
+	}
+	color := p.embedColor(client, message.Channel(), 0)
+
+	pages := paginateLines(lines, weaponFindPageSize, func(description string) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{
+			Title:       title,
+			Color:       color,
+			Description: "```\n" + weaponFindHeader() + "\n" + description + "\n```",
+			Footer:      footer,
+		}
+	})
+
+	p.RLock()
+	err = p.pagination.send(client, message.Channel(), message.UserID(), pages, options.page-1)
+	p.RUnlock()
+
+	if err != nil {
+		log.Printf("Failed to send weapon search results: %s", err)
+	}
+}
+
+func sortWeapons(weapons []*PlanetsideWeapon, sortBy string) {
+	stat, ok := weaponFindStats[sortBy]
+
+	sort.SliceStable(weapons, func(i, j int) bool {
+		if !ok {
+			return strings.ToLower(weapons[i].Name) < strings.ToLower(weapons[j].Name)
+		}
+
+		if stat.ascending {
+			return stat.value(weapons[i]) < stat.value(weapons[j])
+		}
+
+		return stat.value(weapons[i]) > stat.value(weapons[j])
+	})
+}
+
+func weaponFindHeader() string {
+	return fmt.Sprintf("%-*s %-3s %4s %4s %4s %5s", weaponFindNameWidth, "Name", "Fac", "RPM", "Mag", "Dmg", "TTK")
+}
+
+func formatWeaponFindRow(weapon *PlanetsideWeapon) string {
+	name := weapon.Name
+	if runes := []rune(name); len(runes) > weaponFindNameWidth {
+		name = string(runes[:weaponFindNameWidth-1]) + "…"
+	}
+
+	faction := "NS"
+	if weapon.FactionID > 0 {
+		faction = getFactionCode(weapon.FactionID)
+	}
+
+	ttk := "-"
+	if weapon.FireRateMs > 0 && weapon.MaxDamage > 0 {
+		ttk = fmt.Sprintf("%0.f", weaponTTK(weapon))
+	}
+
+	return fmt.Sprintf("%-*s %-3s %4.f %4d %4d %5s", weaponFindNameWidth, name, faction, weaponRPM(weapon), weapon.ClipSize, weapon.MaxDamage, ttk)
+}
@@ -0,0 +1,110 @@
+package planetsidetwoplugin
+
+import (
+	"testing"
+)
+
+func TestParseWeaponFindOptions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []weaponFilter
+		sortBy   string
+		page     int
+		err      string
+	}{
+		{"", nil, "name", 1, ""},
+		{
+			"category=LMG faction=VS rpm>700 mag>=100 sort=ttk",
+			[]weaponFilter{{"category", "=", "LMG"}, {"faction", "=", "VS"}, {"rpm", ">", "700"}, {"mag", ">=", "100"}},
+			"ttk", 1, "",
+		},
+		{`category="Assault Rifle" page=2`, []weaponFilter{{"category", "=", "Assault Rifle"}}, "name", 2, ""},
+		{`"category=heavy weapon"`, []weaponFilter{{"category", "=", "heavy weapon"}}, "name", 1, ""},
+		{"category=“Sniper Rifle”", []weaponFilter{{"category", "=", "Sniper Rifle"}}, "name", 1, ""},
+		{"category=Battle_Rifle", []weaponFilter{{"category", "=", "Battle Rifle"}}, "name", 1, ""},
+		{"  vehicle!=yes   SORT=Reload ", []weaponFilter{{"vehicle", "!=", "yes"}}, "reload", 1, ""},
+		{"category=Assault Rifle", nil, "", 0, "Invalid filter 'Rifle'. Use filters like category=LMG or rpm>700."},
+		{`category="Assault Rifle`, nil, "", 0, `Missing closing quote in 'category="Assault Rifle'.`},
+		{"category>LMG", nil, "", 0, "category can only be compared with = or !=."},
+		{"faction=XX", nil, "", 0, "Unknown faction 'XX'."},
+		{"vehicle=maybe", nil, "", 0, "Invalid vehicle value 'maybe'. Use yes or no."},
+		{"rpm>fast", nil, "", 0, "Invalid number 'fast' for rpm."},
+		{"page=0", nil, "", 0, "Invalid page '0'."},
+		{"sort=cost", nil, "", 0, "Unknown sort 'cost'. Use name, ammo, damage, mag, mindamage, reload, rpm, ttk, velocity."},
+		{"cost<100", nil, "", 0, "Unknown filter 'cost'. Use category, faction, vehicle, ammo, damage, mag, mindamage, reload, rpm, ttk, velocity."},
+	}
+
+	for _, test := range tests {
+		options, err := parseWeaponFindOptions(test.text)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("parseWeaponFindOptions(%q) returned error %v, want %q", test.text, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseWeaponFindOptions(%q) returned error %v", test.text, err)
+			continue
+		}
+
+		if options.sortBy != test.sortBy || options.page != test.page {
+			t.Errorf("parseWeaponFindOptions(%q) sorts by %q on page %d, want %q on page %d", test.text, options.sortBy, options.page, test.sortBy, test.page)
+		}
+
+		if len(options.filters) != len(test.expected) {
+			t.Errorf("parseWeaponFindOptions(%q) = %+v, want %+v", test.text, options.filters, test.expected)
+			continue
+		}
+
+		for i, expected := range test.expected {
+			if options.filters[i] != expected {
+				t.Errorf("parseWeaponFindOptions(%q) filter %d = %+v, want %+v", test.text, i, options.filters[i], expected)
+			}
+		}
+	}
+}
+
+func TestWeaponFilterMatches(t *testing.T) {
+	// 60000 / 75 ms = 800 RPM.
+	lmg := &PlanetsideWeapon{Name: "Orion VS54", Category: "LMG", FactionID: 1, FireRateMs: 75, ClipSize: 100, MaxDamage: 143}
+	rifle := &PlanetsideWeapon{Name: "T1 Cycler", Category: "Assault Rifle", FactionID: 3, FireRateMs: 75, ClipSize: 30, MaxDamage: 143}
+	launcher := &PlanetsideWeapon{Name: "ML-7", Category: "Heavy Weapon", FireRateMs: 0, IsVehicleWeapon: false}
+	cannon := &PlanetsideWeapon{Name: "Saron HRB", Category: "Lightning Weapon", FactionID: 1, IsVehicleWeapon: true}
+
+	tests := []struct {
+		filter   weaponFilter
+		weapon   *PlanetsideWeapon
+		expected bool
+	}{
+		{weaponFilter{"category", "=", "LMG"}, lmg, true},
+		{weaponFilter{"category", "=", "lmg"}, rifle, false},
+		{weaponFilter{"category", "=", "assault rifle"}, rifle, true},
+		{weaponFilter{"category", "=", "AR"}, rifle, true},
+		{weaponFilter{"category", "=", "heavy"}, launcher, true},
+		{weaponFilter{"category", "!=", "LMG"}, lmg, false},
+		{weaponFilter{"faction", "=", "VS"}, lmg, true},
+		{weaponFilter{"faction", "=", "vanu sovereignty"}, lmg, true},
+		{weaponFilter{"faction", "=", "VS"}, rifle, false},
+		{weaponFilter{"faction", "=", "NS"}, launcher, true},
+		{weaponFilter{"faction", "!=", "TR"}, rifle, false},
+		{weaponFilter{"vehicle", "=", "yes"}, cannon, true},
+		{weaponFilter{"vehicle", "=", "no"}, cannon, false},
+		{weaponFilter{"rpm", ">", "700"}, lmg, true},
+		{weaponFilter{"rpm", ">", "800"}, lmg, false},
+		{weaponFilter{"rpm", ">=", "800"}, lmg, true},
+		{weaponFilter{"rpm", ">", "0"}, launcher, false},
+		{weaponFilter{"mag", ">=", "100"}, lmg, true},
+		{weaponFilter{"mag", ">=", "100"}, rifle, false},
+		{weaponFilter{"mag", "<", "100"}, rifle, true},
+		{weaponFilter{"mag", "<=", "30"}, rifle, true},
+		{weaponFilter{"damage", "=", "143"}, lmg, true},
+		{weaponFilter{"damage", "!=", "143"}, lmg, false},
+	}
+
+	for _, test := range tests {
+		if actual := test.filter.matches(test.weapon); actual != test.expected {
+			t.Errorf("%+v matches %s = %t, want %t", test.filter, test.weapon.Name, actual, test.expected)
+		}
+	}
+}
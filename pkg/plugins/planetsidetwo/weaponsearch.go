@@ -0,0 +1,85 @@
+package planetsidetwoplugin
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lampjaw/discordgobot"
+)
+
+// resolveWeaponName finds the weapon the user meant by query and calls onResolved with its
+// exact name. The weapon catalog is searched first, so lookups keep working while the data
+// source is down; the data source is only asked when the catalog has no clear match. When the
+// match is ambiguous the user is asked to pick from the best candidates.
+func (p *planetsidetwoPlugin) resolveWeaponName(client *discordgobot.DiscordClient, message discordgobot.Message, query string, onResolved func(weaponName string)) {
+	query = strings.TrimSpace(query)
+
+	candidates := p.catalogWeaponCandidates(query)
+	ranked := rankWeaponCandidates(query, candidates)
+
+	if !isUnambiguousMatch(ranked) {
+		searched, err := p.dataSource.SearchWeapons(query)
+		if err != nil {
+			log.Printf("Weapon search for '%s' failed: %s", query, err)
+			if len(ranked) == 0 {
+				onResolved(query)
+				return
+			}
+		} else {
+			ranked = rankWeaponCandidates(query, append(candidates, searched...))
+		}
+	}
+
+	if len(ranked) == 0 {
+		p.RLock()
+		client.SendMessage(message.Channel(), fmt.Sprintf("No weapons found matching '%s'.", query))
+		p.RUnlock()
+		return
+	}
+
+	if isUnambiguousMatch(ranked) {
+		onResolved(ranked[0].weapon.Name)
+		return
+	}
+
+	options := make([]string, 0, selectionMaxOptions)
+	for _, candidate := range ranked {
+		if len(options) == selectionMaxOptions {
+			break
+		}
+		options = append(options, candidate.weapon.Name)
+	}
+
+	p.RLock()
+	err := p.selections.prompt(client, message.Channel(), message.UserID(), fmt.Sprintf("Which weapon did you mean by '%s'?", query), p.embedColor(client, message.Channel(), 0), options, onResolved)
+	p.RUnlock()
+
+	if err != nil {
+		log.Printf("Failed to prompt for weapon selection: %s", err)
+		onResolved(ranked[0].weapon.Name)
+	}
+}
+
+// catalogWeaponCandidates returns the cataloged weapons whose names resemble the query.
+func (p *planetsidetwoPlugin) catalogWeaponCandidates(query string) []*PlanetsideWeaponSearchResult {
+	names, err := p.repository.getCatalogWeaponNames()
+	if err != nil {
+		log.Printf("Failed to get cataloged weapon names: %s", err)
+		return nil
+	}
+
+	cataloged := make([]*PlanetsideWeaponSearchResult, len(names))
+	for i, name := range names {
+		cataloged[i] = &PlanetsideWeaponSearchResult{Name: name}
+	}
+
+	matches := closeWeaponCandidates(rankWeaponCandidates(query, cataloged), fuzzyCandidateScore)
+
+	candidates := make([]*PlanetsideWeaponSearchResult, len(matches))
+	for i, candidate := range matches {
+		candidates[i] = candidate.weapon
+	}
+
+	return candidates
+}
@@ -13,14 +13,21 @@ import (
 	"github.com/lampjaw/discordgobot"
 )
 
+// StatsProvider is implemented by plugins that report their own counters in the stats output.
+type StatsProvider interface {
+	Stats() map[string]string
+}
+
 type statsPlugin struct {
 	discordgobot.Plugin
-	version string
+	version   string
+	providers []StatsProvider
 }
 
-func New(appVersion string) *statsPlugin {
+func New(appVersion string, providers ...StatsProvider) *statsPlugin {
 	return &statsPlugin{
-		version: appVersion,
+		version:   appVersion,
+		providers: providers,
 	}
 }
 
@@ -92,6 +99,20 @@ func (p *statsPlugin) runStatsCommand(bot *discordgobot.Gobot, client *discordgo
 		}
 	}
 
+	for _, provider := range p.providers {
+		providerStats := provider.Stats()
+
+		names := make([]string, 0, len(providerStats))
+		for name := range providerStats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintf(w, "%s: \t%s\n", name, providerStats[name])
+		}
+	}
+
 	if client.IsBotOwner(message) {
 		guilds := client.Guilds()
 
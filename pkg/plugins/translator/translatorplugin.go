@@ -8,12 +8,22 @@ import (
 	"github.com/lampjaw/discordgobot"
 )
 
+const defaultEmbedColor = 0x070707
+
+// themeSettings gives access to the per-guild embed colors stored in the guild profile.
+type themeSettings interface {
+	GetGuildTheme(guildID string) (map[string]int, error)
+}
+
 type translatorPlugin struct {
 	discordgobot.Plugin
+	settings themeSettings
 }
 
-func New() discordgobot.IPlugin {
-	return &translatorPlugin{}
+func New(settings themeSettings) discordgobot.IPlugin {
+	return &translatorPlugin{
+		settings: settings,
+	}
 }
 
 func (p *translatorPlugin) Commands() []*discordgobot.CommandDefinition {
@@ -82,7 +92,7 @@ func (p *translatorPlugin) runTwanswateCommand(bot *discordgobot.Gobot, client *
 			Name:    previousMessage.UserName(),
 			IconURL: previousMessage.UserAvatar(),
 		},
-		Color:       0x070707,
+		Color:       p.embedColor(channel.GuildID),
 		Description: translatedText,
 		Timestamp:   timestamp.UTC().Format("2006-01-02T15:04:05-0700"),
 		Footer: &discordgo.MessageEmbedFooter{
@@ -94,3 +104,21 @@ func (p *translatorPlugin) runTwanswateCommand(bot *discordgobot.Gobot, client *
 	client.SendEmbedMessage(message.Channel(), embed)
 	p.RUnlock()
 }
+
+// embedColor returns the guild's house color, if it picked one.
+func (p *translatorPlugin) embedColor(guildID string) int {
+	if p.settings == nil {
+		return defaultEmbedColor
+	}
+
+	theme, err := p.settings.GetGuildTheme(guildID)
+	if err != nil {
+		return defaultEmbedColor
+	}
+
+	if color, ok := theme["house"]; ok {
+		return color
+	}
+
+	return defaultEmbedColor
+}